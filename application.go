@@ -0,0 +1,163 @@
+package gntp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Origin describes the machine and software sending notifications. It is
+// sent as the Origin- headers of every request.
+type Origin struct {
+	MachineName     string
+	SoftwareName    string
+	SoftwareVersion string
+	PlatformName    string
+	PlatformVersion string
+}
+
+// Application is a logical application sending notifications. Several
+// applications can share one Client; each is registered and receives
+// callbacks independently. The Client's ApplicationName and ApplicationIcon
+// describe its default application, used by Register and Notify.
+type Application struct {
+	Name          string
+	Icon          *Resource
+	Notifications []*NotificationType
+	Origin        *Origin
+	Callback      CallbackHandler // Receives the application's callbacks instead of the client's handler
+}
+
+// NewApplication creates an application
+func NewApplication(name string) *Application {
+	return &Application{Name: name}
+}
+
+// WithIcon sets the application icon
+func (a *Application) WithIcon(icon *Resource) *Application {
+	a.Icon = icon
+	return a
+}
+
+// WithNotifications adds notification types
+func (a *Application) WithNotifications(notifications ...*NotificationType) *Application {
+	a.Notifications = append(a.Notifications, notifications...)
+	return a
+}
+
+// WithOrigin sets the Origin- headers
+func (a *Application) WithOrigin(origin Origin) *Application {
+	a.Origin = &origin
+	return a
+}
+
+// WithCallback sets the handler for the application's callbacks
+func (a *Application) WithCallback(handler CallbackHandler) *Application {
+	a.Callback = handler
+	return a
+}
+
+// applicationState is the registration state of one application
+type applicationState struct {
+	app           Application
+	notifications []*NotificationType
+	templates     map[string]*notificationTemplates // By notification name
+
+	mu        sync.Mutex
+	localized map[string]map[string]*localizedTemplates // By locale and notification name
+}
+
+// RegisterApplication registers app and its notification types. If app has
+// a callback handler, the client's callback listener is started.
+func (c *Client) RegisterApplication(ctx context.Context, app *Application) error {
+	if app.Callback != nil {
+		if err := c.listenCallbacks(); err != nil {
+			return err
+		}
+	}
+	return c.registerApplication(ctx, app)
+}
+
+// NotifyApplication sends a notification on behalf of app, which must have
+// been registered with RegisterApplication
+func (c *Client) NotifyApplication(ctx context.Context, app *Application, notificationName, title, text string, options *NotifyOptions) error {
+	return c.notifyApplication(ctx, app, notificationName, title, text, options)
+}
+
+// defaultApplication returns the application described by the client's fields
+func (c *Client) defaultApplication() *Application {
+	return &Application{
+		Name: c.ApplicationName,
+		Icon: c.ApplicationIcon,
+	}
+}
+
+// newApplicationState parses the templates of app's notification types
+func newApplicationState(app *Application, notifications []*NotificationType) *applicationState {
+	state := &applicationState{
+		app:           *app,
+		notifications: notifications,
+		templates:     make(map[string]*notificationTemplates),
+	}
+	for _, notif := range notifications {
+		// Register validated the templates
+		if templates, err := notif.parseTemplates(); err == nil && templates != nil {
+			state.templates[notif.Name] = templates
+		}
+	}
+	return state
+}
+
+// setRegistered records that the application of state is registered
+func (c *Client) setRegistered(state *applicationState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.applications == nil {
+		c.applications = make(map[string]*applicationState)
+	}
+	c.applications[state.app.Name] = state
+}
+
+// registered returns the registration state of the named application, or nil
+func (c *Client) registered(name string) *applicationState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.applications[name]
+}
+
+// callbackFor returns the handler for callbacks to the named application
+func (c *Client) callbackFor(name string) CallbackHandler {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if state, ok := c.applications[name]; ok && state.app.Callback != nil {
+		return state.app.Callback
+	}
+	return c.callbackHandler
+}
+
+// addOrigin adds the Origin- headers of the application to req
+func (a *Application) addOrigin(req *Request) {
+	if a.Origin == nil {
+		return
+	}
+
+	for _, header := range []Header{
+		{"Origin-Machine-Name", a.Origin.MachineName},
+		{"Origin-Software-Name", a.Origin.SoftwareName},
+		{"Origin-Software-Version", a.Origin.SoftwareVersion},
+		{"Origin-Platform-Name", a.Origin.PlatformName},
+		{"Origin-Platform-Version", a.Origin.PlatformVersion},
+	} {
+		if header.Value != "" {
+			req.Headers.Add(header.Name, header.Value)
+		}
+	}
+}
+
+// errNotRegistered is returned when notifying for an unregistered application
+func errNotRegistered(app *Application) error {
+	return fmt.Errorf("must register application %q before notifying", app.Name)
+}
@@ -0,0 +1,390 @@
+package gntp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy controls what NotifyAsync does when the queue is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until a worker frees a slot in the queue
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropOldest discards the oldest queued notification to make room
+	OverflowDropOldest
+
+	// OverflowDropNewest discards the notification being enqueued
+	OverflowDropNewest
+)
+
+const (
+	// DefaultAsyncWorkers is the number of workers started by NotifyAsync
+	// when WithAsync was not called
+	DefaultAsyncWorkers = 1
+
+	// DefaultAsyncQueueSize is the queue capacity used when WithAsync was not called
+	DefaultAsyncQueueSize = 100
+)
+
+var (
+	// ErrQueueFull is returned by NotifyAsync when the notification was dropped
+	// because the queue is full and the policy is OverflowDropNewest
+	ErrQueueFull = errors.New("gntp: async queue is full")
+
+	// ErrShutdown is returned by NotifyAsync after Shutdown or Close
+	ErrShutdown = errors.New("gntp: client is shut down")
+)
+
+// AsyncStats contains counters for the asynchronous sender
+type AsyncStats struct {
+	Enqueued uint64 // Notifications accepted into the queue
+	Sent     uint64 // Notifications delivered successfully
+	Failed   uint64 // Notifications that returned an error
+	Dropped  uint64 // Notifications discarded by the overflow policy
+	Queued   int    // Notifications currently waiting in the queue
+}
+
+// asyncJob is a queued notification
+type asyncJob struct {
+	notificationName string
+	title            string
+	text             string
+	options          *NotifyOptions
+}
+
+// asyncQueue is a bounded queue served by a fixed pool of workers
+type asyncQueue struct {
+	jobs    chan asyncJob
+	policy  OverflowPolicy
+	quit    chan struct{}
+	stopped sync.Once
+	workers sync.WaitGroup
+
+	// ctx bounds the sends of the workers; stop cancels it
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	pending int           // Queued or in-flight jobs
+	idle    chan struct{} // Closed when pending drops to zero
+	closed  bool
+
+	enqueued atomic.Uint64
+	sent     atomic.Uint64
+	failed   atomic.Uint64
+	dropped  atomic.Uint64
+}
+
+// WithAsync configures the worker pool used by NotifyAsync.
+// It must be called before the first NotifyAsync; later calls are ignored.
+func (c *Client) WithAsync(workers, queueSize int, policy OverflowPolicy) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.async == nil {
+		c.async = c.startAsync(workers, queueSize, policy)
+	}
+	return c
+}
+
+// WithAsyncErrorHandler sets a function called with errors from asynchronous sends,
+// including rate limit summaries
+func (c *Client) WithAsyncErrorHandler(handler func(err error)) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.asyncErrorHandler = handler
+	return c
+}
+
+// NotifyAsync queues a notification and returns without waiting for Growl.
+// Delivery errors are reported to the handler set with WithAsyncErrorHandler.
+func (c *Client) NotifyAsync(notificationName, title, text string, options *NotifyOptions) error {
+	return c.notifyAsync(notificationName, title, text, options, false)
+}
+
+// notifyAsync queues a notification. With noWait, a full OverflowBlock queue
+// drops the notification and returns ErrQueueFull instead of waiting.
+func (c *Client) notifyAsync(notificationName, title, text string, options *NotifyOptions, noWait bool) error {
+	c.mu.Lock()
+	if c.async == nil {
+		c.async = c.startAsync(DefaultAsyncWorkers, DefaultAsyncQueueSize, OverflowBlock)
+	}
+	q := c.async
+	c.mu.Unlock()
+
+	if options == nil {
+		options = NewNotifyOptions()
+	}
+	// Copy options so the caller may reuse them
+	opts := *options
+
+	policy := q.policy
+	if noWait && policy == OverflowBlock {
+		policy = OverflowDropNewest
+	}
+	return q.enqueue(asyncJob{
+		notificationName: notificationName,
+		title:            title,
+		text:             text,
+		options:          &opts,
+	}, policy)
+}
+
+// Flush waits until every queued notification has been sent or ctx is done
+func (c *Client) Flush(ctx context.Context) error {
+	c.mu.Lock()
+	q := c.async
+	c.mu.Unlock()
+
+	if q == nil {
+		return nil
+	}
+	return q.flush(ctx)
+}
+
+// Shutdown stops accepting asynchronous notifications, drains the queue and
+// closes the client. If ctx is done before the queue is drained, the remaining
+// notifications are discarded, sends in progress are cancelled and ctx.Err()
+// is returned without waiting for them.
+func (c *Client) Shutdown(ctx context.Context) error {
+	c.mu.Lock()
+	q := c.async
+	c.mu.Unlock()
+
+	var err error
+	if q != nil {
+		q.close()
+		err = q.flush(ctx)
+		if stopErr := q.stop(ctx); err == nil {
+			err = stopErr
+		}
+	}
+
+	if closeErr := c.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// AsyncStats returns the counters of the asynchronous sender
+func (c *Client) AsyncStats() AsyncStats {
+	c.mu.Lock()
+	q := c.async
+	c.mu.Unlock()
+
+	if q == nil {
+		return AsyncStats{}
+	}
+	return AsyncStats{
+		Enqueued: q.enqueued.Load(),
+		Sent:     q.sent.Load(),
+		Failed:   q.failed.Load(),
+		Dropped:  q.dropped.Load(),
+		Queued:   len(q.jobs),
+	}
+}
+
+// startAsync creates the queue and starts its workers
+func (c *Client) startAsync(workers, queueSize int, policy OverflowPolicy) *asyncQueue {
+	if workers < 1 {
+		workers = DefaultAsyncWorkers
+	}
+	if queueSize < 1 {
+		queueSize = DefaultAsyncQueueSize
+	}
+
+	q := &asyncQueue{
+		jobs:   make(chan asyncJob, queueSize),
+		policy: policy,
+		quit:   make(chan struct{}),
+	}
+	q.ctx, q.cancel = context.WithCancel(context.Background())
+
+	for i := 0; i < workers; i++ {
+		q.workers.Add(1)
+		go c.asyncWorker(q)
+	}
+	return q
+}
+
+// asyncWorker sends queued notifications until the queue is stopped
+func (c *Client) asyncWorker(q *asyncQueue) {
+	defer q.workers.Done()
+
+	for {
+		select {
+		case job := <-q.jobs:
+			if q.ctx.Err() != nil {
+				// Stopped while the job was waiting
+				q.dropped.Add(1)
+				q.done()
+				return
+			}
+			err := c.NotifyContext(q.ctx, job.notificationName, job.title, job.text, job.options)
+			if err != nil {
+				q.failed.Add(1)
+				c.reportAsyncError(err)
+			} else {
+				q.sent.Add(1)
+			}
+			q.done()
+		case <-q.quit:
+			return
+		}
+	}
+}
+
+// reportAsyncError passes err to the async error handler, if any
+func (c *Client) reportAsyncError(err error) {
+	c.mu.Lock()
+	handler := c.asyncErrorHandler
+	c.mu.Unlock()
+
+	if handler != nil {
+		handler(err)
+	}
+}
+
+// enqueue adds a job according to the overflow policy
+func (q *asyncQueue) enqueue(job asyncJob, policy OverflowPolicy) error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return ErrShutdown
+	}
+	q.add()
+	q.mu.Unlock()
+
+	switch policy {
+	case OverflowDropNewest:
+		select {
+		case q.jobs <- job:
+		default:
+			q.dropped.Add(1)
+			q.done()
+			return ErrQueueFull
+		}
+
+	case OverflowDropOldest:
+		for {
+			select {
+			case q.jobs <- job:
+				q.enqueued.Add(1)
+				if q.ctx.Err() != nil {
+					q.drain()
+				}
+				return nil
+			default:
+			}
+			// Queue is full: discard the oldest job and try again
+			select {
+			case <-q.jobs:
+				q.dropped.Add(1)
+				q.done()
+			default:
+			}
+		}
+
+	default: // OverflowBlock
+		select {
+		case q.jobs <- job:
+		case <-q.quit:
+			q.done()
+			return ErrShutdown
+		}
+	}
+
+	q.enqueued.Add(1)
+	if q.ctx.Err() != nil {
+		// Stopped while the job was being added: no worker will take it
+		q.drain()
+	}
+	return nil
+}
+
+// add records a new pending job; q.mu must be held
+func (q *asyncQueue) add() {
+	if q.pending == 0 {
+		q.idle = make(chan struct{})
+	}
+	q.pending++
+}
+
+// done records that a pending job finished or was discarded
+func (q *asyncQueue) done() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.pending--
+	if q.pending == 0 {
+		close(q.idle)
+	}
+}
+
+// flush waits until no jobs are pending
+func (q *asyncQueue) flush(ctx context.Context) error {
+	q.mu.Lock()
+	if q.pending == 0 {
+		q.mu.Unlock()
+		return nil
+	}
+	idle := q.idle
+	q.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close rejects further jobs
+func (q *asyncQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+}
+
+// drain discards the queued jobs, counting them as dropped
+func (q *asyncQueue) drain() {
+	for {
+		select {
+		case <-q.jobs:
+			q.dropped.Add(1)
+			q.done()
+		default:
+			return
+		}
+	}
+}
+
+// stop terminates the workers, discarding queued jobs and cancelling sends
+// in progress, and waits for them to return or ctx to be done
+func (q *asyncQueue) stop(ctx context.Context) error {
+	q.close()
+	q.stopped.Do(func() {
+		close(q.quit)
+		q.cancel()
+	})
+	q.drain()
+
+	finished := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,126 @@
+package gntp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestShutdownDeliversQueue(t *testing.T) {
+	server := newFakeServer(t)
+	client := server.client("Async").WithAsync(2, 10, OverflowBlock)
+	if err := client.Register([]*NotificationType{NewNotificationType("job")}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := client.NotifyAsync("job", "Done", "", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := client.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(server.received("NOTIFY")); got != 5 {
+		t.Errorf("received %d notifications, want 5", got)
+	}
+	if err := client.NotifyAsync("job", "Late", "", nil); !errors.Is(err, ErrShutdown) {
+		t.Errorf("NotifyAsync after Shutdown = %v, want ErrShutdown", err)
+	}
+}
+
+func TestShutdownHonoursDeadline(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(server *fakeServer, client *Client)
+	}{
+		{"rate limit delay", func(server *fakeServer, client *Client) {
+			client.WithRateLimit(RateLimit{Rate: 0.1, Burst: 1, Action: RateLimitDelay})
+		}},
+		{"slow server", func(server *fakeServer, client *Client) {
+			server.setDelay(10 * time.Second)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t)
+			client := server.client("Async").WithAsync(1, 10, OverflowBlock)
+			if err := client.Register([]*NotificationType{NewNotificationType("job")}); err != nil {
+				t.Fatal(err)
+			}
+			tt.setup(server, client)
+
+			for i := 0; i < 3; i++ {
+				client.NotifyAsync("job", "Done", "", nil)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			err := client.Shutdown(ctx)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Shutdown = %v, want DeadlineExceeded", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Shutdown took %v after a 200ms deadline", elapsed)
+			}
+		})
+	}
+}
+
+func TestOverflowDropNewest(t *testing.T) {
+	server := newFakeServer(t)
+	server.setDelay(time.Second)
+	client := server.client("Async").WithAsync(1, 1, OverflowDropNewest)
+	if err := client.Register([]*NotificationType{NewNotificationType("job")}); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var full int
+	for i := 0; i < 5; i++ {
+		if errors.Is(client.NotifyAsync("job", "Done", "", nil), ErrQueueFull) {
+			full++
+		}
+	}
+	if full == 0 {
+		t.Error("no notification was dropped")
+	}
+	if stats := client.AsyncStats(); stats.Dropped != uint64(full) {
+		t.Errorf("Dropped = %d, want %d", stats.Dropped, full)
+	}
+}
+
+func TestCloseDropsQueue(t *testing.T) {
+	server := newFakeServer(t)
+	server.setDelay(time.Second)
+	client := server.client("Async").WithAsync(1, 10, OverflowBlock)
+	if err := client.Register([]*NotificationType{NewNotificationType("job")}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := client.NotifyAsync("job", "Done", "", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := client.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown after Close = %v", err)
+	}
+
+	stats := client.AsyncStats()
+	if stats.Queued != 0 {
+		t.Errorf("Queued = %d after Close, want 0", stats.Queued)
+	}
+	if stats.Sent+stats.Failed+stats.Dropped != 5 {
+		t.Errorf("stats = %+v, want all 5 notifications accounted for", stats)
+	}
+}
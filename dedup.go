@@ -0,0 +1,146 @@
+package gntp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// dedupEntry tracks a notification being sent or sent within the current
+// window
+type dedupEntry struct {
+	key              string
+	app              *Application
+	notificationName string
+	title            string
+	text             string
+	options          NotifyOptions
+	suppressed       int
+	timer            *time.Timer // nil while the notification is being sent
+}
+
+// deduplicator suppresses identical notifications within a time window
+type deduplicator struct {
+	mu      sync.Mutex
+	window  time.Duration
+	report  bool
+	entries map[string]*dedupEntry
+	stopped bool
+}
+
+// WithDedup suppresses notifications identical to one sent less than window
+// ago. Notifications are identical when they have the same name, title and
+// text, or the same name and NotifyOptions.DedupKey. If report is true, the
+// first notification is sent again when the window ends, with the number of
+// suppressed duplicates appended to its title ("Disk full ×12"). A
+// notification that fails to send does not start a window.
+func (c *Client) WithDedup(window time.Duration, report bool) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.dedup != nil {
+		c.dedup.stop()
+	}
+	c.dedup = &deduplicator{
+		window:  window,
+		report:  report,
+		entries: make(map[string]*dedupEntry),
+	}
+	return c
+}
+
+// dedupKey returns the key identifying duplicates of a notification
+func dedupKey(app *Application, notificationName, title, text string, options *NotifyOptions) string {
+	if options.DedupKey != "" {
+		return app.Name + "\x00" + notificationName + "\x00" + options.DedupKey
+	}
+	return app.Name + "\x00" + notificationName + "\x00" + title + "\x00" + text
+}
+
+// suppress reports whether the notification duplicates one being sent or
+// sent within the window, counting it if so. Otherwise it returns a pending
+// entry that suppresses duplicates while the notification is sent; the
+// caller passes it to sent or failed.
+func (d *deduplicator) suppress(app *Application, notificationName, title, text string, options *NotifyOptions) (*dedupEntry, bool) {
+	key := dedupKey(app, notificationName, title, text, options)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if entry, ok := d.entries[key]; ok {
+		entry.suppressed++
+		return nil, true
+	}
+	if d.stopped {
+		return nil, false
+	}
+
+	entry := &dedupEntry{
+		key:              key,
+		app:              app,
+		notificationName: notificationName,
+		title:            title,
+		text:             text,
+		options:          *options,
+	}
+	d.entries[key] = entry
+	return entry, false
+}
+
+// sent starts the window of a pending entry whose notification was delivered
+func (d *deduplicator) sent(c *Client, entry *dedupEntry) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.entries[entry.key] != entry {
+		return
+	}
+	entry.timer = time.AfterFunc(d.window, func() {
+		d.expire(c, entry)
+	})
+}
+
+// failed drops a pending entry whose notification was not delivered, so an
+// identical retry is sent
+func (d *deduplicator) failed(entry *dedupEntry) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.entries[entry.key] == entry {
+		delete(d.entries, entry.key)
+	}
+}
+
+// expire ends the window of an entry and reports its duplicates
+func (d *deduplicator) expire(c *Client, entry *dedupEntry) {
+	d.mu.Lock()
+	if d.entries[entry.key] == entry {
+		delete(d.entries, entry.key)
+	}
+	report := d.report && !d.stopped && entry.suppressed > 0
+	d.mu.Unlock()
+
+	if !report {
+		return
+	}
+
+	title := fmt.Sprintf("%s ×%d", entry.title, entry.suppressed)
+	if _, err := c.notifyLimited(context.Background(), entry.app, entry.notificationName, title, entry.text, &entry.options); err != nil {
+		c.reportAsyncError(err)
+	}
+}
+
+// stop cancels pending reports
+func (d *deduplicator) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.stopped = true
+	for key, entry := range d.entries {
+		if entry.timer != nil {
+			entry.timer.Stop()
+		}
+		delete(d.entries, key)
+	}
+}
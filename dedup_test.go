@@ -0,0 +1,95 @@
+package gntp
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDedupSuppressesDuplicates(t *testing.T) {
+	server := newFakeServer(t)
+	client := server.client("Dedup").WithDedup(time.Minute, false)
+	if err := client.Register([]*NotificationType{NewNotificationType("disk")}); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	for i := 0; i < 3; i++ {
+		if err := client.Notify("disk", "Disk full", "/dev/sda1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client.Notify("disk", "Disk full", "/dev/sdb1")
+
+	if got := len(server.received("NOTIFY")); got != 2 {
+		t.Errorf("received %d notifications, want 2", got)
+	}
+}
+
+func TestDedupRetriesFailedSend(t *testing.T) {
+	server := newFakeServer(t)
+	client := server.client("Dedup").WithDedup(time.Minute, true)
+	if err := client.Register([]*NotificationType{NewNotificationType("disk")}); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// Point the client at a port nobody listens on
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := client.Port
+	client.Port = ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	if err := client.Notify("disk", "Disk full", ""); err == nil {
+		t.Fatal("Notify to a closed port succeeded")
+	}
+	if err := client.Notify("disk", "Disk full", ""); err == nil {
+		t.Error("retry was suppressed as a duplicate of a failed notification")
+	}
+
+	client.Port = port
+	if err := client.Notify("disk", "Disk full", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(server.received("NOTIFY")); got != 1 {
+		t.Errorf("received %d notifications, want 1", got)
+	}
+
+	client.dedup.mu.Lock()
+	defer client.dedup.mu.Unlock()
+	for _, entry := range client.dedup.entries {
+		if entry.suppressed != 0 {
+			t.Errorf("failed sends counted as %d suppressed duplicates", entry.suppressed)
+		}
+	}
+}
+
+func TestDedupSuppressesConcurrentDuplicates(t *testing.T) {
+	server := newFakeServer(t)
+	server.setDelay(100 * time.Millisecond)
+	client := server.client("Dedup").WithDedup(time.Minute, false)
+	if err := client.Register([]*NotificationType{NewNotificationType("disk")}); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := client.Notify("disk", "Disk full", "/dev/sda1"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := len(server.received("NOTIFY")); got != 1 {
+		t.Errorf("received %d notifications, want 1", got)
+	}
+}
@@ -0,0 +1,225 @@
+package gntp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDigestMaxDelay is how long a digest buffers notifications by default
+	DefaultDigestMaxDelay = 30 * time.Second
+
+	// DefaultDigestMaxBatch is the default number of notifications per summary
+	DefaultDigestMaxBatch = 50
+)
+
+// DigestItem is a notification buffered by a Digest
+type DigestItem struct {
+	NotificationName string
+	Title            string
+	Text             string
+	Options          NotifyOptions
+	Timestamp        time.Time
+}
+
+// DigestGroupFunc returns the key of the batch an item belongs to
+type DigestGroupFunc func(item DigestItem) string
+
+// DigestFormatter renders the title and text of a summary notification
+type DigestFormatter func(group string, items []DigestItem) (title, text string)
+
+// DigestOptions configures a Digest
+type DigestOptions struct {
+	MaxDelay  time.Duration   // How long the first item of a batch may wait
+	MaxBatch  int             // Batch size that triggers an immediate flush
+	GroupBy   DigestGroupFunc // Defaults to GroupByName
+	Formatter DigestFormatter // Defaults to DefaultDigestFormatter
+}
+
+// Digest buffers notifications and sends each batch as one summary notification.
+// The summary is sent with the notification name of the first item in the
+// batch, the highest priority of the batch and sticky if any item was sticky.
+type Digest struct {
+	client  *Client
+	options DigestOptions
+
+	mu      sync.Mutex
+	batches map[string]*digestBatch
+	closed  bool
+	sending sync.WaitGroup // Batches being sent
+}
+
+// digestBatch is a group of items waiting to be summarized
+type digestBatch struct {
+	items []DigestItem
+	timer *time.Timer
+}
+
+// GroupByName groups digest items by notification name
+func GroupByName(item DigestItem) string {
+	return item.NotificationName
+}
+
+// DefaultDigestFormatter titles the summary with the item count and lists the
+// item titles, one per line
+func DefaultDigestFormatter(group string, items []DigestItem) (string, string) {
+	title := fmt.Sprintf("%d %s notifications", len(items), group)
+
+	lines := make([]string, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, item.Title)
+	}
+	return title, strings.Join(lines, "\n")
+}
+
+// NewDigest creates a digest sender wrapping client
+func NewDigest(client *Client, options DigestOptions) *Digest {
+	if options.MaxDelay <= 0 {
+		options.MaxDelay = DefaultDigestMaxDelay
+	}
+	if options.MaxBatch < 1 {
+		options.MaxBatch = DefaultDigestMaxBatch
+	}
+	if options.GroupBy == nil {
+		options.GroupBy = GroupByName
+	}
+	if options.Formatter == nil {
+		options.Formatter = DefaultDigestFormatter
+	}
+
+	return &Digest{
+		client:  client,
+		options: options,
+		batches: make(map[string]*digestBatch),
+	}
+}
+
+// Notify buffers a notification
+func (d *Digest) Notify(notificationName, title, text string) error {
+	return d.NotifyWithOptions(notificationName, title, text, NewNotifyOptions())
+}
+
+// NotifyWithOptions buffers a notification with options. The batch is sent
+// when it reaches MaxBatch items or MaxDelay after its first item.
+func (d *Digest) NotifyWithOptions(notificationName, title, text string, options *NotifyOptions) error {
+	if options == nil {
+		options = NewNotifyOptions()
+	}
+	item := DigestItem{
+		NotificationName: notificationName,
+		Title:            title,
+		Text:             text,
+		Options:          *options,
+		Timestamp:        time.Now(),
+	}
+	group := d.options.GroupBy(item)
+
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return ErrShutdown
+	}
+
+	batch, ok := d.batches[group]
+	if !ok {
+		batch = &digestBatch{}
+		batch.timer = time.AfterFunc(d.options.MaxDelay, func() {
+			if err := d.flushGroup(group, batch); err != nil {
+				d.client.reportAsyncError(err)
+			}
+		})
+		d.batches[group] = batch
+	}
+	batch.items = append(batch.items, item)
+	full := len(batch.items) >= d.options.MaxBatch
+	d.mu.Unlock()
+
+	if full {
+		return d.flushGroup(group, batch)
+	}
+	return nil
+}
+
+// Flush sends all buffered batches immediately
+func (d *Digest) Flush() error {
+	d.mu.Lock()
+	groups := make([]string, 0, len(d.batches))
+	batches := make(map[string]*digestBatch, len(d.batches))
+	for group, batch := range d.batches {
+		groups = append(groups, group)
+		batches[group] = batch
+	}
+	d.mu.Unlock()
+
+	sort.Strings(groups)
+
+	var firstErr error
+	for _, group := range groups {
+		if err := d.flushGroup(group, batches[group]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Close rejects further notifications, flushes all buffered batches and
+// waits for batches already being sent. The wrapped client is not closed.
+func (d *Digest) Close() error {
+	d.mu.Lock()
+	d.closed = true
+	d.mu.Unlock()
+
+	err := d.Flush()
+	d.sending.Wait()
+	return err
+}
+
+// flushGroup sends one batch unless it was already sent
+func (d *Digest) flushGroup(group string, batch *digestBatch) error {
+	d.mu.Lock()
+	if d.batches[group] != batch {
+		d.mu.Unlock()
+		return nil
+	}
+	delete(d.batches, group)
+	batch.timer.Stop()
+	items := batch.items
+	d.sending.Add(1)
+	d.mu.Unlock()
+	defer d.sending.Done()
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	if len(items) == 1 {
+		// Nothing to summarize
+		item := items[0]
+		return d.client.NotifyWithOptions(item.NotificationName, item.Title, item.Text, &item.Options)
+	}
+
+	title, text := d.options.Formatter(group, items)
+	return d.client.NotifyWithOptions(items[0].NotificationName, title, text, summaryOptions(items))
+}
+
+// summaryOptions merges the options of a batch
+func summaryOptions(items []DigestItem) *NotifyOptions {
+	options := NewNotifyOptions()
+	options.Priority = items[0].Options.Priority
+
+	for _, item := range items {
+		if item.Options.Priority > options.Priority {
+			options.Priority = item.Options.Priority
+		}
+		if item.Options.Sticky {
+			options.Sticky = true
+		}
+		if options.Icon == nil {
+			options.Icon = item.Options.Icon
+		}
+	}
+	return options
+}
@@ -0,0 +1,77 @@
+package gntp
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDigestSummarizesBatch(t *testing.T) {
+	server := newFakeServer(t)
+	client := server.client("Digest")
+	if err := client.Register([]*NotificationType{NewNotificationType("build")}); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	digest := NewDigest(client, DigestOptions{MaxDelay: time.Hour, MaxBatch: 3})
+	for _, title := range []string{"a", "b", "c"} {
+		if err := digest.Notify("build", title, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	received := server.received("NOTIFY")
+	if len(received) != 1 {
+		t.Fatalf("received %d notifications, want 1 summary", len(received))
+	}
+	if got := received[0].Headers["Notification-Title"]; got != "3 build notifications" {
+		t.Errorf("summary title = %q", got)
+	}
+}
+
+func TestDigestCloseRejectsAndFlushes(t *testing.T) {
+	server := newFakeServer(t)
+	client := server.client("Digest")
+	if err := client.Register([]*NotificationType{NewNotificationType("build")}); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	digest := NewDigest(client, DigestOptions{MaxDelay: 5 * time.Millisecond})
+
+	// Notifications racing Close are either flushed by it or rejected
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := digest.Notify("build", "x", "")
+			if err != nil && !errors.Is(err, ErrShutdown) {
+				t.Error(err)
+			}
+			if err == nil {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond)
+	if err := digest.Close(); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	sent := len(server.received("NOTIFY"))
+	time.Sleep(20 * time.Millisecond)
+	if late := len(server.received("NOTIFY")); late != sent {
+		t.Errorf("%d notifications were sent after Close returned", late-sent)
+	}
+	if accepted > 0 && sent == 0 {
+		t.Errorf("%d accepted notifications were never sent", accepted)
+	}
+}
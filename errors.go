@@ -0,0 +1,58 @@
+package gntp
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// GNTP error codes returned in the Error-Code header
+const (
+	ErrorTimedOut               = 200
+	ErrorNetworkFailure         = 201
+	ErrorInvalidRequest         = 300
+	ErrorUnknownProtocol        = 301
+	ErrorUnknownProtocolVersion = 302
+	ErrorRequiredHeaderMissing  = 303
+	ErrorNotAuthorized          = 400
+	ErrorUnknownApplication     = 401
+	ErrorUnknownNotification    = 402
+	ErrorAlreadyProcessed       = 403
+	ErrorNotificationDisabled   = 404
+	ErrorInternalServerError    = 500
+)
+
+// ServerError is returned when the server answers with -ERROR
+type ServerError struct {
+	Code        int       // Error-Code header, 0 if missing
+	Description string    // Error-Description header
+	Response    *Response // Full response
+}
+
+// Error implements the error interface
+func (e *ServerError) Error() string {
+	if e.Code == 0 && e.Description == "" {
+		return fmt.Sprintf("server error: %s", e.Response.Raw)
+	}
+	return fmt.Sprintf("server error %d: %s", e.Code, e.Description)
+}
+
+// newServerError creates a ServerError from an -ERROR response
+func newServerError(resp *Response) *ServerError {
+	code, _ := strconv.Atoi(resp.Headers.Get("Error-Code"))
+	return &ServerError{
+		Code:        code,
+		Description: resp.Headers.Get("Error-Description"),
+		Response:    resp,
+	}
+}
+
+// ErrorCode returns the GNTP error code carried by err, or 0 if err is not
+// a ServerError
+func ErrorCode(err error) int {
+	var serverErr *ServerError
+	if errors.As(err, &serverErr) {
+		return serverErr.Code
+	}
+	return 0
+}
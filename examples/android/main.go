@@ -26,7 +26,7 @@ func main() {
 	client := gntp.NewClient("Android Example").
 		WithHost(androidHost).
 		WithPort(23053).
-		WithIconMode(gntp.IconModeDataURL).  // Best for Android
+		WithProfile(gntp.ProfileGrowlAndroid). // Small data URL icons
 		WithTimeout(15 * time.Second).        // Longer timeout for mobile
 		WithDebug(false)
 	
@@ -71,8 +71,9 @@ func main() {
 	fmt.Println("    • Binary   - GNTP spec (binary data)")
 	fmt.Println("    • DataURL  - Base64 embedded (no line breaks)")
 	fmt.Println("    • FileURL  - Absolute file path (file:///...)")
-	fmt.Println("\nRecommendations:")
-	fmt.Println("  • Windows Growl → Binary mode (works best)")
-	fmt.Println("  • Android Growl → DataURL mode")
-	fmt.Println("  • macOS Growl   → Binary mode")
+	fmt.Println("\nProfiles (see gntp.Profile):")
+	fmt.Println("  • Windows Growl → gntp.ProfileGrowlWindows (Binary)")
+	fmt.Println("  • Android Growl → gntp.ProfileGrowlAndroid (DataURL, small icons)")
+	fmt.Println("  • macOS Growl   → gntp.ProfileGrowlMac (Binary)")
+	fmt.Println("  • Snarl         → gntp.ProfileSnarl (Binary)")
 }
\ No newline at end of file
@@ -0,0 +1,114 @@
+// Tracing example: a minimal Tracer that prints spans.
+//
+// To use OpenTelemetry instead, implement gntp.Tracer on top of an
+// otel trace.Tracer:
+//
+//	Start:   ctx, span := otelTracer.Start(ctx, name); return ctx, otelSpan{span}
+//	Inject:  propagation.TraceContext{}.Inject(ctx, carrier); return carrier.Get("traceparent")
+//	Extract: carrier.Set("traceparent", value); return propagation.TraceContext{}.Extract(ctx, carrier)
+//
+// and map AddEvent, RecordError and End to the otel span methods.
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/cumulus13/go-gntp"
+)
+
+type spanKey struct{}
+
+// printSpan is a span that prints itself when it ends
+type printSpan struct {
+	name    string
+	traceID string
+	spanID  string
+	start   time.Time
+}
+
+func (s *printSpan) AddEvent(name string, attrs ...slog.Attr) {
+	fmt.Printf("  event %s on %s (trace %s) %v\n", name, s.name, s.traceID, attrs)
+}
+
+func (s *printSpan) RecordError(err error) {
+	fmt.Printf("  error in %s: %v\n", s.name, err)
+}
+
+func (s *printSpan) End() {
+	fmt.Printf("  span %-18s trace=%s span=%s %s\n", s.name, s.traceID, s.spanID, time.Since(s.start))
+}
+
+// printTracer propagates W3C traceparent values
+type printTracer struct{}
+
+func (printTracer) Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, gntp.Span) {
+	span := &printSpan{name: name, spanID: randomHex(8), start: time.Now()}
+	if parent, ok := ctx.Value(spanKey{}).(*printSpan); ok {
+		span.traceID = parent.traceID
+	} else {
+		span.traceID = randomHex(16)
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (printTracer) Inject(ctx context.Context) string {
+	span, ok := ctx.Value(spanKey{}).(*printSpan)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", span.traceID, span.spanID)
+}
+
+func (printTracer) Extract(ctx context.Context, value string) context.Context {
+	parts := strings.Split(value, "-")
+	if len(parts) != 4 {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, &printSpan{traceID: parts[1], spanID: parts[2]})
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func main() {
+	fmt.Println("=== GNTP Tracing Example ===")
+	fmt.Println()
+
+	client := gntp.NewClient("Tracing Example").
+		WithTracer(printTracer{})
+
+	if err := client.WithCallback(func(info gntp.CallbackInfo) {
+		fmt.Printf("Callback %s for %s\n", info.Type, info.NotificationID)
+	}); err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+
+	notification := gntp.NewNotificationType("alert").
+		WithDisplayName("Alert")
+
+	fmt.Println("Registering...")
+	if err := client.Register([]*gntp.NotificationType{notification}); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Sending...")
+	options := gntp.NewNotifyOptions().WithCallbackContext("traced")
+	if err := client.NotifyWithOptions("alert", "Traced", "Click me to see the linked callback span", options); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println()
+	fmt.Println("Waiting 30 seconds for callbacks...")
+	time.Sleep(30 * time.Second)
+}
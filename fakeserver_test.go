@@ -0,0 +1,240 @@
+package gntp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRequest is a request received by a fakeServer
+type fakeRequest struct {
+	Type      string // REGISTER or NOTIFY
+	Headers   map[string]string
+	Sections  []map[string]string
+	Resources map[string][]byte // By identifier
+	Raw       []byte            // Bytes of the request as received
+}
+
+// fakeServer is a GNTP server on a loopback port that records requests
+type fakeServer struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	requests []*fakeRequest
+	respond  func(req *fakeRequest) string // Returns the response; OK if nil
+	delay    time.Duration                 // Wait before responding
+	closed   chan struct{}
+}
+
+// newFakeServer starts a server that answers every request with OK
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{ln: ln, closed: make(chan struct{})}
+	go s.serve()
+	t.Cleanup(func() {
+		close(s.closed)
+		ln.Close()
+	})
+	return s
+}
+
+// client returns a client for the server
+func (s *fakeServer) client(applicationName string) *Client {
+	return NewClient(applicationName).
+		WithHost("127.0.0.1").
+		WithPort(s.ln.Addr().(*net.TCPAddr).Port).
+		WithTimeout(5 * time.Second)
+}
+
+// setRespond sets the function that builds responses
+func (s *fakeServer) setRespond(respond func(req *fakeRequest) string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.respond = respond
+}
+
+// setDelay makes the server wait before responding
+func (s *fakeServer) setDelay(delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.delay = delay
+}
+
+// received returns the requests of the given type received so far
+func (s *fakeServer) received(requestType string) []*fakeRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var requests []*fakeRequest
+	for _, req := range s.requests {
+		if req.Type == requestType {
+			requests = append(requests, req)
+		}
+	}
+	return requests
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	var raw bytes.Buffer
+	r := bufio.NewReader(io.TeeReader(conn, &raw))
+
+	// A request may reference resources sent earlier without their blocks
+	more := func() bool {
+		if r.Buffered() > 0 {
+			return true
+		}
+		conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		defer conn.SetReadDeadline(time.Time{})
+		_, err := r.Peek(1)
+		return err == nil
+	}
+
+	req, err := readFakeRequest(r, more)
+	if err != nil {
+		return
+	}
+	req.Raw = raw.Bytes()
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	respond, delay := s.respond, s.delay
+	s.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-s.closed:
+			return
+		}
+	}
+
+	response := "GNTP/1.0 -OK NONE\r\nResponse-Action: " + req.Type + "\r\n\r\n"
+	if respond != nil {
+		response = respond(req)
+	}
+	io.WriteString(conn, response)
+}
+
+// readFakeRequest parses a request, its notification type sections and the
+// binary resources its headers reference, as long as more reports that
+// another resource block follows
+func readFakeRequest(r *bufio.Reader, more func() bool) (*fakeRequest, error) {
+	line, err := readFakeLine(r)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("bad request line %q", line)
+	}
+
+	req := &fakeRequest{Type: fields[1], Resources: make(map[string][]byte)}
+	if req.Headers, err = readFakeHeaders(r); err != nil {
+		return nil, err
+	}
+
+	count, _ := strconv.Atoi(req.Headers["Notifications-Count"])
+	for i := 0; i < count; i++ {
+		section, err := readFakeHeaders(r)
+		if err != nil {
+			return nil, err
+		}
+		req.Sections = append(req.Sections, section)
+	}
+
+	for range req.references() {
+		if !more() {
+			break
+		}
+		block, err := readFakeHeaders(r)
+		if err != nil {
+			return nil, err
+		}
+		length, _ := strconv.Atoi(block["Length"])
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		if _, err := r.Discard(len(CRLF + CRLF)); err != nil {
+			return nil, err
+		}
+		req.Resources[block["Identifier"]] = data
+	}
+	return req, nil
+}
+
+// readFakeLine reads up to CRLF; a bare LF is part of a multi-line value
+func readFakeLine(r *bufio.Reader) (string, error) {
+	var line strings.Builder
+	for !strings.HasSuffix(line.String(), CRLF) {
+		part, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		line.WriteString(part)
+	}
+	return strings.TrimSuffix(line.String(), CRLF), nil
+}
+
+// readFakeHeaders reads headers up to a blank line
+func readFakeHeaders(r *bufio.Reader) (map[string]string, error) {
+	headers := make(map[string]string)
+	for {
+		line, err := readFakeLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if line == "" {
+			return headers, nil
+		}
+		if name, value, ok := strings.Cut(line, ": "); ok {
+			headers[name] = value
+		}
+	}
+}
+
+// references returns the identifiers of the binary resources referenced by
+// the request headers
+func (req *fakeRequest) references() map[string]bool {
+	refs := make(map[string]bool)
+	blocks := append([]map[string]string{req.Headers}, req.Sections...)
+	for _, block := range blocks {
+		for _, value := range block {
+			if id, ok := strings.CutPrefix(value, "x-growl-resource://"); ok {
+				refs[id] = true
+			}
+		}
+	}
+	return refs
+}
+
+// errorResponse returns an error response with the given code
+func errorResponse(code int, description string) string {
+	return fmt.Sprintf("GNTP/1.0 -ERROR NONE\r\nError-Code: %d\r\nError-Description: %s\r\n\r\n", code, description)
+}
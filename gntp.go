@@ -7,21 +7,43 @@
 //   - Windows Growl compatibility
 //   - Android Growl compatibility
 //   - Retry mechanism
-//   - Resource deduplication
+//   - Asynchronous sending with a bounded worker pool
+//   - Rate limiting per client and per notification type
+//   - Duplicate suppression
+//   - Interceptor chain around every request
+//   - Structured logging with log/slog
+//   - Metrics for expvar and Prometheus
+//   - Tracing hooks for requests and callbacks
+//   - Resource deduplication by content hash
+//   - Icon resizing and size limits
+//   - Image type detection from content
+//   - Loading icons from files, fs.FS, readers and URLs
+//   - Automatic icon mode selection per server
+//   - Embedded HTTP server for icons
+//   - Compatibility profiles for Growl for Windows, Mac, Android and Snarl
+//   - Server capability probing
+//   - Persistent registration state to skip unchanged registrations
+//   - Several applications over one client
+//   - Validation before sending
+//   - Title and text templates
+//   - Markdown and HTML text converted to plain text
+//   - Translated display names and templates per locale
+//   - Icons streamed from files and readers instead of held in memory
 package gntp
 
 import (
-	// "crypto/md5"
+	"context"
+	"crypto/md5"
 	"encoding/base64"
 	"fmt"
-	// "io"
+	"io"
+	"log/slog"
 	"net"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
-
-	"github.com/google/uuid"
 )
 
 const (
@@ -40,22 +62,26 @@ type IconMode int
 
 const (
 	// IconModeBinary sends icons as binary resources (GNTP spec compliant)
-	// Not recommended for Windows Growl due to bugs
+	// See the predefined profiles for the servers it suits
 	IconModeBinary IconMode = iota
 	
 	// IconModeFileURL references icons via file:// URLs
 	// Requires icon files to exist on disk
 	IconModeFileURL
 	
-	// IconModeDataURL embeds icons as base64 data URLs (RECOMMENDED)
-	// Best compatibility across all platforms
+	// IconModeDataURL embeds icons as base64 data URLs
+	// Large icons may fail; see IconProcessing
 	IconModeDataURL
 	
 	// IconModeHttpURL references icons via http:// or https:// URLs
-	// Best for remote servers and Android
+	// Best for remote servers and Android. Icons without a URL are served
+	// by the icon server if one is running (see WithIconServer).
 	IconModeHttpURL
 	
-	// IconModeAuto automatically selects best mode (defaults to DataURL)
+	// IconModeAuto selects a mode per icon and destination: binary for
+	// servers known to handle it, the icon's URL if it has one, a file URL
+	// when Growl runs locally and the file exists, and a data URL otherwise.
+	// If the server rejects a request, it is retried with data URLs.
 	IconModeAuto
 )
 
@@ -75,30 +101,38 @@ const (
 
 // CallbackInfo contains information about a callback event
 type CallbackInfo struct {
+	Application       string // Application-Name the notification was sent for
 	Type              CallbackType
 	NotificationID    string
 	Context           string
 	ContextType       string
 	Timestamp         time.Time
+	Data              map[string]string // Data- headers returned by the server
 }
 
 // CallbackHandler is a function that handles callback events
 type CallbackHandler func(info CallbackInfo)
 
-// Resource represents an icon resource
+// Resource represents an icon resource. Its content is Data, or for
+// resources created with NewResourceReaderAt, NewResourceOpener and
+// LoadResourceStream, a stream read when the resource is sent.
 type Resource struct {
 	Identifier string
 	Data       []byte
 	SourcePath string
 	MimeType   string
+
+	stream *resourceStream
 }
 
 // NotificationType defines a type of notification
 type NotificationType struct {
-	Name        string
-	DisplayName string
-	Enabled     bool
-	Icon        *Resource
+	Name          string
+	DisplayName   string
+	Enabled       bool
+	Icon          *Resource
+	TitleTemplate string // text/template source rendered by NotifyData
+	TextTemplate  string // text/template source rendered by NotifyData
 }
 
 // NotifyOptions contains options for sending notifications
@@ -108,6 +142,10 @@ type NotifyOptions struct {
 	Icon            *Resource
 	CallbackContext string   // Custom data passed to callback
 	CallbackTarget  string   // URL to open on click
+	DedupKey        string   // Identifies duplicates instead of title and text
+	CoalescingID    string   // Replaces an earlier notification with the same ID
+	TextFormat      TextFormat // Markup converted to plain text before sending
+	LinkCallback    bool     // Use the first link in the text as CallbackTarget
 }
 
 // Message is a simplified notification structure (for compatibility)
@@ -131,10 +169,32 @@ type Client struct {
 	IconMode         IconMode
 	Debug            bool
 	Timeout          time.Duration
-	registered       bool
 	callbackListener net.Listener
 	callbackHandler  CallbackHandler
 	callbackURL      string
+
+	mu                sync.Mutex
+	async             *asyncQueue
+	asyncErrorHandler func(err error)
+	limiter           *rateLimiter
+	dedup             *deduplicator
+	interceptors      []Interceptor
+	logger            *slog.Logger
+	debugLogger       *slog.Logger
+	metrics           Metrics
+	tracer            Tracer
+	resourceCache     *ResourceCache
+	destinations      map[string]*destinationState
+	iconServer        *iconServer
+	fixedProfile      *Profile
+	applications      map[string]*applicationState
+	registrationStore RegistrationStore
+	catalog           *Catalog
+	locale            string
+
+	iconProcessing     *IconProcessing
+	modeIconProcessing map[IconMode]IconProcessing
+	iconCache          *IconCache
 }
 
 // NewClient creates a new GNTP client
@@ -146,7 +206,6 @@ func NewClient(applicationName string) *Client {
 		IconMode:        IconModeDataURL, // Safe default
 		Debug:           false,
 		Timeout:         10 * time.Second,
-		registered:      false,
 	}
 }
 
@@ -174,7 +233,7 @@ func (c *Client) WithIcon(icon *Resource) *Client {
 	return c
 }
 
-// WithDebug enables debug output
+// WithDebug enables debug logging to stdout when no logger is set
 func (c *Client) WithDebug(debug bool) *Client {
 	c.Debug = debug
 	return c
@@ -188,7 +247,18 @@ func (c *Client) WithTimeout(timeout time.Duration) *Client {
 
 // WithCallback sets up callback handler
 func (c *Client) WithCallback(handler CallbackHandler) error {
+	c.mu.Lock()
 	c.callbackHandler = handler
+	c.mu.Unlock()
+	
+	return c.listenCallbacks()
+}
+
+// listenCallbacks starts the callback listener unless it is running
+func (c *Client) listenCallbacks() error {
+	if c.callbackListener != nil {
+		return nil
+	}
 	
 	// Start callback listener
 	listener, err := net.Listen("tcp", ":0") // Random port
@@ -202,6 +272,8 @@ func (c *Client) WithCallback(handler CallbackHandler) error {
 	addr := listener.Addr().(*net.TCPAddr)
 	c.callbackURL = fmt.Sprintf("http://%s:%d", getLocalIP(), addr.Port)
 	
+	c.log().Debug("gntp callback listener started", slog.String("url", c.callbackURL))
+	
 	// Start accepting callbacks
 	go c.acceptCallbacks()
 	
@@ -229,31 +301,48 @@ func (c *Client) handleCallback(conn net.Conn) {
 		return
 	}
 	
-	response := string(buf[:n])
-	lines := strings.Split(response, CRLF)
+	response := parseResponse(string(buf[:n]))
 	
 	info := CallbackInfo{
-		Timestamp: time.Now(),
+		Application:    response.Headers.Get("Application-Name"),
+		Type:           CallbackType(response.Headers.Get("Notification-Callback-Result")),
+		NotificationID: response.Headers.Get("Notification-ID"),
+		Context:        response.Headers.Get("Notification-Callback-Context"),
+		ContextType:    response.Headers.Get("Notification-Callback-Context-Type"),
+		Timestamp:      time.Now(),
 	}
 	
-	for _, line := range lines {
-		if strings.HasPrefix(line, "Notification-Callback-Result: ") {
-			info.Type = CallbackType(strings.TrimPrefix(line, "Notification-Callback-Result: "))
-		} else if strings.HasPrefix(line, "Notification-ID: ") {
-			info.NotificationID = strings.TrimPrefix(line, "Notification-ID: ")
-		} else if strings.HasPrefix(line, "Notification-Callback-Context: ") {
-			info.Context = strings.TrimPrefix(line, "Notification-Callback-Context: ")
-		} else if strings.HasPrefix(line, "Notification-Callback-Context-Type: ") {
-			info.ContextType = strings.TrimPrefix(line, "Notification-Callback-Context-Type: ")
+	for _, header := range response.Headers {
+		if strings.HasPrefix(header.Name, "Data-") {
+			if info.Data == nil {
+				info.Data = make(map[string]string)
+			}
+			info.Data[header.Name] = header.Value
 		}
 	}
 	
 	// Send OK response
 	conn.Write([]byte(fmt.Sprintf("GNTP/%s -OK NONE%s%s", GNTPVersion, CRLF, CRLF)))
 	
-	// Call handler
-	if c.callbackHandler != nil {
-		c.callbackHandler(info)
+	c.log().Debug("gntp callback received",
+		slog.String("type", string(info.Type)),
+		slog.String("notification_id", info.NotificationID),
+	)
+	
+	if metrics := c.instruments(); metrics != nil {
+		metrics.CallbackReceived(info.Type)
+	}
+	
+	// Link the callback to the span that sent the notification
+	tracer := c.tracing()
+	ctx := tracer.Extract(context.Background(), info.Data[TraceHeader])
+	_, span := tracer.Start(ctx, "gntp.callback")
+	span.AddEvent(string(info.Type), slog.String("gntp.notification_id", info.NotificationID))
+	span.End()
+	
+	// Call the handler of the application, or the client's
+	if handler := c.callbackFor(info.Application); handler != nil {
+		handler(info)
 	}
 }
 
@@ -275,73 +364,72 @@ func getLocalIP() string {
 	return "127.0.0.1"
 }
 
-// LoadResource loads an icon from a file
+// LoadResource loads an icon from a file. The MIME type is detected from
+// the content; files that are not a supported image fail with ErrNotImage.
 func LoadResource(path string) (*Resource, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 	
-	mimeType := guessMimeType(path)
+	// The content decides the type; extensions are often wrong
+	mimeType := SniffMimeType(data)
+	if mimeType == "" {
+		return nil, fmt.Errorf("%s: %w", path, ErrNotImage)
+	}
 	
 	return &Resource{
-		Identifier: uuid.New().String(),
+		Identifier: ResourceID(data),
 		Data:       data,
 		SourcePath: path,
 		MimeType:   mimeType,
 	}, nil
 }
 
-// LoadResourceFromBytes creates a resource from byte data
+// LoadResourceFromBytes creates a resource from byte data. An empty or
+// application/octet-stream mimeType is detected from the data. The data is
+// not validated; use NewResource to reject non-image payloads.
 func LoadResourceFromBytes(data []byte, mimeType string) *Resource {
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		if sniffed := SniffMimeType(data); sniffed != "" {
+			mimeType = sniffed
+		}
+	}
+	
 	return &Resource{
-		Identifier: uuid.New().String(),
+		Identifier: ResourceID(data),
 		Data:       data,
 		MimeType:   mimeType,
 	}
 }
 
-// guessMimeType guesses MIME type from file extension
-func guessMimeType(path string) string {
-	ext := strings.ToLower(filepath.Ext(path))
-	switch ext {
-	case ".png":
-		return "image/png"
-	case ".jpg", ".jpeg":
-		return "image/jpeg"
-	case ".gif":
-		return "image/gif"
-	case ".bmp":
-		return "image/bmp"
-	case ".ico":
-		return "image/x-icon"
-	case ".svg":
-		return "image/svg+xml"
-	case ".webp":
-		return "image/webp"
-	default:
-		return "application/octet-stream"
-	}
+// ResourceID returns the identifier for resource data: the hex MD5 of the
+// data, as recommended by the GNTP spec. Identical icons share an identifier.
+func ResourceID(data []byte) string {
+	return fmt.Sprintf("%x", md5.Sum(data))
 }
 
 // GetReference returns the icon reference string based on mode (PUBLIC - UPPERCASE!)
 func (r *Resource) GetReference(mode IconMode) string {
-	return r.getReference(mode)
+	ref, _ := r.getReference(mode)
+	return ref
 }
 
-// ToDataURL converts to base64 data URL (PUBLIC)
+// ToDataURL converts to base64 data URL (PUBLIC). It returns "" if a
+// streamed resource cannot be read.
 func (r *Resource) ToDataURL() string {
-	return r.toDataURL()
+	ref, _ := r.dataURL()
+	return ref
 }
 
 // getReference returns the icon reference string based on mode
-func (r *Resource) getReference(mode IconMode) string {
+func (r *Resource) getReference(mode IconMode) (string, error) {
 	switch mode {
 	case IconModeBinary:
-		return fmt.Sprintf("x-growl-resource://%s", r.Identifier)
+		return fmt.Sprintf("x-growl-resource://%s", r.Identifier), nil
 		
 	case IconModeFileURL:
-		if r.SourcePath != "" {
+		if r.SourcePath != "" && !r.isURL() {
 			// Convert to absolute path and use file:// format
 			absPath := r.SourcePath
 			if !filepath.IsAbs(absPath) {
@@ -352,33 +440,47 @@ func (r *Resource) getReference(mode IconMode) string {
 			// Windows: C:\path\to\file -> file:///C:/path/to/file
 			// Unix: /path/to/file -> file:///path/to/file
 			path := strings.ReplaceAll(absPath, "\\", "/")
-			return fmt.Sprintf("file:///%s", path)
+			return fmt.Sprintf("file:///%s", path), nil
 		}
 		// Fallback to data URL if no file path
-		return r.toDataURL()
+		return r.dataURL()
 		
 	case IconModeHttpURL:
-		if r.SourcePath != "" && (strings.HasPrefix(r.SourcePath, "http://") || strings.HasPrefix(r.SourcePath, "https://")) {
-			return r.SourcePath // Already a URL
+		if r.isURL() {
+			return r.SourcePath, nil // Already a URL
 		}
-		return r.toDataURL()
+		return r.dataURL()
 		
 	default: // IconModeDataURL, IconModeAuto
-		return r.toDataURL()
+		return r.dataURL()
 	}
 }
 
-// toDataURL converts resource to base64 data URL
-// CRITICAL: Growl for Windows is VERY STRICT about base64 format!
-func (r *Resource) toDataURL() string {
-	// Use standard base64 encoding WITHOUT line breaks
-	// Growl for Windows doesn't like MIME-style line breaks in data URLs
-	encoded := base64.StdEncoding.EncodeToString(r.Data)
+// isURL reports whether the resource was loaded from an HTTP URL
+func (r *Resource) isURL() bool {
+	return strings.HasPrefix(r.SourcePath, "http://") || strings.HasPrefix(r.SourcePath, "https://")
+}
+
+// dataURL converts resource to base64 data URL, encoding the content as it
+// is read. The base64 has no line breaks, since a header value cannot span
+// lines.
+func (r *Resource) dataURL() (string, error) {
+	var sb strings.Builder
+	sb.Grow(len("data:;base64,") + len(r.MimeType) + base64.StdEncoding.EncodedLen(int(r.Size())))
+	sb.WriteString("data:" + r.MimeType + ";base64,")
 	
-	// Don't add line breaks for data URLs - they cause issues!
-	// The MIME line break format is only for binary resources
+	content, err := r.Open()
+	if err != nil {
+		return "", err
+	}
+	defer content.Close()
 	
-	return fmt.Sprintf("data:%s;base64,%s", r.MimeType, encoded)
+	encoder := base64.NewEncoder(base64.StdEncoding, &sb)
+	if _, err := io.CopyN(encoder, content, r.Size()); err != nil {
+		return "", fmt.Errorf("failed to read resource: %w", err)
+	}
+	encoder.Close()
+	return sb.String(), nil
 }
 
 // NewNotificationType creates a new notification type
@@ -443,14 +545,48 @@ func (no *NotifyOptions) WithCallbackContext(context string) *NotifyOptions {
 	return no
 }
 
+// WithCoalescingID sets the ID of an earlier notification this one replaces
+func (no *NotifyOptions) WithCoalescingID(id string) *NotifyOptions {
+	no.CoalescingID = id
+	return no
+}
+
 // WithCallbackTarget sets the URL to open on click
 func (no *NotifyOptions) WithCallbackTarget(target string) *NotifyOptions {
 	no.CallbackTarget = target
 	return no
 }
 
-// Close closes the callback listener
+// WithDedupKey sets the key used to detect duplicate notifications
+func (no *NotifyOptions) WithDedupKey(key string) *NotifyOptions {
+	no.DedupKey = key
+	return no
+}
+
+// Close stops the async workers, dropping queued notifications, and closes
+// the callback listener. Use Shutdown to deliver queued notifications first.
 func (c *Client) Close() error {
+	c.mu.Lock()
+	q := c.async
+	limiter := c.limiter
+	dedup := c.dedup
+	icons := c.iconServer
+	c.iconServer = nil
+	c.mu.Unlock()
+	
+	if q != nil {
+		q.stop(context.Background())
+	}
+	if limiter != nil {
+		limiter.stop()
+	}
+	if dedup != nil {
+		dedup.stop()
+	}
+	if icons != nil {
+		icons.close()
+	}
+	
 	if c.callbackListener != nil {
 		return c.callbackListener.Close()
 	}
@@ -1,5 +1,3 @@
 module github.com/cumulus13/go-gntp
 
 go 1.25.3
-
-require github.com/google/uuid v1.6.0
\ No newline at end of file
@@ -0,0 +1,317 @@
+package gntp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+
+	// Register decoders for image.Decode
+	_ "image/gif"
+	_ "image/jpeg"
+)
+
+// minIconDimension is the smallest size an icon is shrunk to when enforcing MaxBytes
+const minIconDimension = 16
+
+// DefaultIconCacheSize is the size of the icon cache each client creates
+const DefaultIconCacheSize = 8 << 20
+
+// MaxIconPixels is the largest image, in pixels, that is decoded for
+// processing. Larger images are rejected before decoding, since an untrusted
+// image of a few kilobytes can claim dimensions that need gigabytes.
+var MaxIconPixels = 16 << 20
+
+// ErrIconTooLarge is returned when an icon exceeds IconProcessing.MaxBytes
+var ErrIconTooLarge = errors.New("gntp: icon too large")
+
+// IconProcessing configures how icons are resized and re-encoded before sending
+type IconProcessing struct {
+	MaxDimension int // Downscale icons whose width or height exceeds this; 0 keeps the size
+	MaxBytes     int // Reject icons larger than this after processing; 0 disables the limit
+}
+
+// iconCacheKey identifies a processed icon
+type iconCacheKey struct {
+	identifier string
+	processing IconProcessing
+}
+
+// IconCache keeps processed icons by content hash and settings, evicting the
+// least recently used when their total size exceeds the limit. It is safe
+// for concurrent use and may be shared by several clients.
+type IconCache struct {
+	icons *lru[iconCacheKey, *Resource]
+}
+
+// NewIconCache creates a cache holding up to maxBytes of processed icons
+func NewIconCache(maxBytes int64) *IconCache {
+	return &IconCache{icons: newLRU[iconCacheKey](maxBytes, (*Resource).Size)}
+}
+
+// Process is Resource.Process with the result cached
+func (ic *IconCache) Process(r *Resource, p IconProcessing) (*Resource, error) {
+	if p.MaxDimension <= 0 && p.MaxBytes <= 0 {
+		return r, nil
+	}
+
+	key := iconCacheKey{identifier: r.Identifier, processing: p}
+	if cached, ok := ic.icons.get(key); ok {
+		return cached, nil
+	}
+
+	processed, err := r.Process(p)
+	if err != nil {
+		return nil, err
+	}
+
+	// Icons returned unchanged are cheap to check again and not worth keeping
+	if processed != r {
+		ic.icons.add(key, processed)
+	}
+	return processed, nil
+}
+
+// Process returns the icon downscaled and re-encoded as PNG according to p.
+// PNG, JPEG and GIF icons are decoded; other formats are only checked
+// against MaxBytes. Icons that need no change are returned as is. A
+// processed icon has no SourcePath because it no longer matches the file.
+// Images over MaxIconPixels fail with ErrIconTooLarge. Clients cache the
+// results in an IconCache.
+func (r *Resource) Process(p IconProcessing) (*Resource, error) {
+	if p.MaxDimension <= 0 && p.MaxBytes <= 0 {
+		return r, nil
+	}
+
+	size := r.Size()
+
+	config, err := r.decodeConfig()
+	if err != nil {
+		// Not a format we can decode (SVG, ICO, BMP, WebP): only check the size
+		if p.MaxBytes > 0 && size > int64(p.MaxBytes) {
+			return nil, fmt.Errorf("%w: %d bytes exceeds %d and %s cannot be resized", ErrIconTooLarge, size, p.MaxBytes, r.MimeType)
+		}
+		return r, nil
+	}
+
+	tooWide := p.MaxDimension > 0 && (config.Width > p.MaxDimension || config.Height > p.MaxDimension)
+	tooBig := p.MaxBytes > 0 && size > int64(p.MaxBytes)
+	if !tooWide && !tooBig {
+		return r, nil
+	}
+
+	if config.Width <= 0 || config.Height <= 0 || config.Width > MaxIconPixels/config.Height {
+		return nil, fmt.Errorf("%w: %dx%d exceeds %d pixels", ErrIconTooLarge, config.Width, config.Height, MaxIconPixels)
+	}
+
+	data, err := r.content()
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode icon: %w", err)
+	}
+
+	dimension := max(config.Width, config.Height)
+	if tooWide {
+		dimension = p.MaxDimension
+	}
+
+	for {
+		data, err := encodePNG(scaleImage(img, dimension))
+		if err != nil {
+			return nil, err
+		}
+
+		if p.MaxBytes <= 0 || len(data) <= p.MaxBytes {
+			return &Resource{
+				Identifier: ResourceID(data),
+				Data:       data,
+				MimeType:   "image/png",
+			}, nil
+		}
+
+		if dimension <= minIconDimension {
+			return nil, fmt.Errorf("%w: %d bytes exceeds %d at %dpx", ErrIconTooLarge, len(data), p.MaxBytes, dimension)
+		}
+		dimension = max(dimension/2, minIconDimension)
+	}
+}
+
+// decodeConfig reads the image dimensions from the start of the content
+func (r *Resource) decodeConfig() (image.Config, error) {
+	content, err := r.Open()
+	if err != nil {
+		return image.Config{}, err
+	}
+	defer content.Close()
+
+	config, _, err := image.DecodeConfig(content)
+	return config, err
+}
+
+// scaleImage downscales img so neither side exceeds dimension, averaging the
+// source pixels covered by each destination pixel
+func scaleImage(img image.Image, dimension int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= dimension && height <= dimension {
+		return img
+	}
+
+	dstWidth, dstHeight := dimension, dimension
+	if width > height {
+		dstHeight = max(1, height*dimension/width)
+	} else {
+		dstWidth = max(1, width*dimension/height)
+	}
+
+	// Work on premultiplied RGBA so transparent pixels don't bleed color
+	src := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		y0 := y * height / dstHeight
+		y1 := max((y+1)*height/dstHeight, y0+1)
+
+		for x := 0; x < dstWidth; x++ {
+			x0 := x * width / dstWidth
+			x1 := max((x+1)*width/dstWidth, x0+1)
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					px := row[sx*4 : sx*4+4]
+					sum[0] += int(px[0])
+					sum[1] += int(px[1])
+					sum[2] += int(px[2])
+					sum[3] += int(px[3])
+				}
+			}
+
+			n := (x1 - x0) * (y1 - y0)
+			i := dst.PixOffset(x, y)
+			dst.Pix[i+0] = uint8(sum[0] / n)
+			dst.Pix[i+1] = uint8(sum[1] / n)
+			dst.Pix[i+2] = uint8(sum[2] / n)
+			dst.Pix[i+3] = uint8(sum[3] / n)
+		}
+	}
+	return dst
+}
+
+// encodePNG encodes img as a compressed PNG
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode icon: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// WithIconProcessing processes icons before they are sent. Without modes
+// the settings apply to every icon mode; otherwise only to the given modes.
+// Settings for a specific mode take precedence over settings for all modes.
+func (c *Client) WithIconProcessing(p IconProcessing, modes ...IconMode) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(modes) == 0 {
+		c.iconProcessing = &p
+		return c
+	}
+
+	if c.modeIconProcessing == nil {
+		c.modeIconProcessing = make(map[IconMode]IconProcessing)
+	}
+	for _, mode := range modes {
+		c.modeIconProcessing[mode] = p
+	}
+	return c
+}
+
+// processIcon applies the icon processing configured for the first of
+// modes that has settings, the settings for all modes, or the profile's
+func (c *Client) processIcon(icon *Resource, modes ...IconMode) (*Resource, error) {
+	c.mu.Lock()
+	var p IconProcessing
+	ok := false
+	for _, mode := range modes {
+		if p, ok = c.modeIconProcessing[mode]; ok {
+			break
+		}
+	}
+	if !ok && c.iconProcessing != nil {
+		p, ok = *c.iconProcessing, true
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		if profile := c.profile(); profile != nil && profile.IconProcessing != nil {
+			p, ok = *profile.IconProcessing, true
+		}
+	}
+
+	if !ok {
+		return icon, nil
+	}
+	return c.icons().Process(icon, p)
+}
+
+// WithIconCache makes the client cache processed icons in cache, which may
+// be shared with other clients. Each client otherwise has its own cache of
+// DefaultIconCacheSize bytes.
+func (c *Client) WithIconCache(cache *IconCache) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.iconCache = cache
+	return c
+}
+
+// icons returns the client's icon cache, creating it if needed
+func (c *Client) icons() *IconCache {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.iconCache == nil {
+		c.iconCache = NewIconCache(DefaultIconCacheSize)
+	}
+	return c.iconCache
+}
+
+// iconReference processes an icon and returns the header value referencing
+// it, attaching the icon to req when it is sent as a binary resource
+func (c *Client) iconReference(req *Request, icon *Resource) (string, error) {
+	mode := c.IconMode
+	if mode == IconModeAuto {
+		mode = c.autoIconMode(icon)
+		if mode != IconModeDataURL {
+			req.autoIcons = true
+		}
+	}
+
+	icon, err := c.processIcon(icon, mode, c.IconMode)
+	if err != nil {
+		return "", err
+	}
+
+	if mode == IconModeBinary {
+		req.addResource(icon)
+	}
+	if mode == IconModeHttpURL && !icon.isURL() {
+		if server := c.servedIcons(); server != nil {
+			if url, ok := server.add(icon); ok {
+				return url, nil
+			}
+			return icon.getReference(IconModeDataURL)
+		}
+	}
+	return icon.getReference(mode)
+}
@@ -0,0 +1,120 @@
+package gntp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// testPNG returns a PNG of the given size with varied pixels
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 7), uint8(y * 13), uint8(x ^ y), 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessDownscales(t *testing.T) {
+	icon := LoadResourceFromBytes(testPNG(t, 300, 150), "")
+
+	tests := []struct {
+		name       string
+		processing IconProcessing
+		width      int
+		height     int
+		unchanged  bool
+	}{
+		{"no limits", IconProcessing{}, 300, 150, true},
+		{"within limits", IconProcessing{MaxDimension: 512}, 300, 150, true},
+		{"too wide", IconProcessing{MaxDimension: 100}, 100, 50, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processed, err := icon.Process(tt.processing)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (processed == icon) != tt.unchanged {
+				t.Errorf("unchanged = %v, want %v", processed == icon, tt.unchanged)
+			}
+			config, err := png.DecodeConfig(bytes.NewReader(processed.Data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Width != tt.width || config.Height != tt.height {
+				t.Errorf("size = %dx%d, want %dx%d", config.Width, config.Height, tt.width, tt.height)
+			}
+		})
+	}
+}
+
+func TestProcessRejectsHugeDimensions(t *testing.T) {
+	data := testPNG(t, 4, 4)
+
+	// Claim 100000x100000 pixels in the IHDR chunk and fix its checksum
+	binary.BigEndian.PutUint32(data[16:], 100000)
+	binary.BigEndian.PutUint32(data[20:], 100000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	icon := LoadResourceFromBytes(data, "image/png")
+	if _, err := icon.Process(IconProcessing{MaxDimension: 64}); !errors.Is(err, ErrIconTooLarge) {
+		t.Errorf("Process = %v, want ErrIconTooLarge", err)
+	}
+}
+
+func TestIconCacheIsBounded(t *testing.T) {
+	processing := IconProcessing{MaxDimension: 32}
+	first, err := LoadResourceFromBytes(testPNG(t, 64, 64), "").Process(processing)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Room for about three processed icons
+	cache := NewIconCache(3*first.Size() + first.Size()/2)
+	for i := 0; i < 10; i++ {
+		icon := LoadResourceFromBytes(testPNG(t, 64+i, 64), "")
+		if _, err := cache.Process(icon, processing); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := cache.icons.len(); n > 3 {
+		t.Errorf("cache holds %d icons, want at most 3", n)
+	}
+
+	// The most recent icon is still cached
+	icon := LoadResourceFromBytes(testPNG(t, 73, 64), "")
+	a, _ := cache.Process(icon, processing)
+	b, _ := cache.Process(icon, processing)
+	if a != b {
+		t.Error("recent icon was not served from the cache")
+	}
+}
+
+func TestClientsHaveSeparateIconCaches(t *testing.T) {
+	a, b := NewClient("A"), NewClient("B")
+	if a.icons() == b.icons() {
+		t.Error("clients share an icon cache")
+	}
+
+	shared := NewIconCache(DefaultIconCacheSize)
+	a.WithIconCache(shared)
+	b.WithIconCache(shared)
+	if a.icons() != shared || b.icons() != shared {
+		t.Error("WithIconCache was not used")
+	}
+}
@@ -0,0 +1,178 @@
+package gntp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultIconMaxAge is how long clients may cache icons from the icon server
+	DefaultIconMaxAge = 24 * time.Hour
+
+	// DefaultIconServerSize is how many bytes of icons the icon server keeps
+	DefaultIconServerSize = 32 << 20
+)
+
+// IconServerOptions configures the embedded icon server
+type IconServerOptions struct {
+	Addr           string        // Listen address; default ":0" (random port)
+	AdvertisedHost string        // Host used in icon URLs; default the local IP
+	MaxAge         time.Duration // Cache-Control max-age; default DefaultIconMaxAge
+	MaxBytes       int64         // Icons kept for serving, least recently used evicted first; default DefaultIconServerSize
+}
+
+// iconServer serves icons over HTTP by content hash
+type iconServer struct {
+	listener net.Listener
+	server   *http.Server
+	baseURL  string
+	maxAge   time.Duration
+
+	icons *lru[string, *Resource] // By identifier
+}
+
+// mimeExtensions maps image MIME types to file extensions for icon URLs
+var mimeExtensions = map[string]string{
+	"image/png":     ".png",
+	"image/jpeg":    ".jpg",
+	"image/gif":     ".gif",
+	"image/bmp":     ".bmp",
+	"image/x-icon":  ".ico",
+	"image/webp":    ".webp",
+	"image/svg+xml": ".svg",
+}
+
+// WithIconServer starts an HTTP server that serves icons by content hash,
+// so IconModeHttpURL can reference any icon as
+// http://<host>:<port>/icons/<hash>.<ext>. IconModeAuto also uses it instead
+// of data URLs. The server stops when the client is closed. Icons that no
+// longer fit in MaxBytes stop being served; an icon larger than MaxBytes is
+// sent as a data URL instead.
+func (c *Client) WithIconServer(opts IconServerOptions) error {
+	if opts.Addr == "" {
+		opts.Addr = ":0"
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = DefaultIconMaxAge
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultIconServerSize
+	}
+
+	// Decide and start under the lock so concurrent calls start one server
+	c.mu.Lock()
+	if c.iconServer != nil {
+		c.mu.Unlock()
+		return errors.New("icon server already running")
+	}
+
+	listener, err := net.Listen("tcp", opts.Addr)
+	if err != nil {
+		c.mu.Unlock()
+		return fmt.Errorf("failed to start icon server: %w", err)
+	}
+
+	host := opts.AdvertisedHost
+	if host == "" {
+		host = getLocalIP()
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	s := &iconServer{
+		listener: listener,
+		baseURL:  "http://" + net.JoinHostPort(host, strconv.Itoa(port)),
+		maxAge:   opts.MaxAge,
+		icons:    newLRU[string](opts.MaxBytes, (*Resource).Size),
+	}
+	s.server = &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	c.iconServer = s
+	c.mu.Unlock()
+
+	c.log().Debug("gntp icon server started", slog.String("url", s.baseURL))
+
+	go s.server.Serve(listener)
+	return nil
+}
+
+// add makes icon available and returns its URL, or false if the icon is
+// too large to keep
+func (s *iconServer) add(icon *Resource) (string, bool) {
+	if !s.icons.add(icon.Identifier, icon) {
+		return "", false
+	}
+	return fmt.Sprintf("%s/icons/%s%s", s.baseURL, icon.Identifier, mimeExtensions[icon.MimeType]), true
+}
+
+// ServeHTTP serves GET and HEAD requests for /icons/<hash>.<ext>
+func (s *iconServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name, ok := strings.CutPrefix(r.URL.Path, "/icons/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	identifier, _, _ := strings.Cut(name, ".")
+
+	icon, ok := s.icons.get(identifier)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Icons are addressed by content hash, so they never change
+	w.Header().Set("Content-Type", icon.MimeType)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d, immutable", int(s.maxAge.Seconds())))
+	w.Header().Set("ETag", strconv.Quote(icon.Identifier))
+
+	content, err := icon.Open()
+	if err != nil {
+		http.Error(w, "icon unavailable", http.StatusInternalServerError)
+		return
+	}
+	defer content.Close()
+
+	// Streamed icons that cannot seek are read into memory for range requests
+	seeker, ok := content.(io.ReadSeeker)
+	if !ok {
+		data, err := icon.content()
+		if err != nil {
+			http.Error(w, "icon unavailable", http.StatusInternalServerError)
+			return
+		}
+		seeker = bytes.NewReader(data)
+	}
+	http.ServeContent(w, r, "", time.Time{}, seeker)
+}
+
+// close stops the server
+func (s *iconServer) close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	return s.server.Shutdown(ctx)
+}
+
+// servedIcons returns the icon server, or nil
+func (c *Client) servedIcons() *iconServer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.iconServer
+}
@@ -0,0 +1,96 @@
+package gntp
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWithIconServerStartsOnce(t *testing.T) {
+	client := NewClient("Icons")
+	defer client.Close()
+
+	const calls = 8
+	errs := make(chan error, calls)
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- client.WithIconServer(IconServerOptions{Addr: "127.0.0.1:0", AdvertisedHost: "127.0.0.1"})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var started int
+	for err := range errs {
+		if err == nil {
+			started++
+		}
+	}
+	if started != 1 {
+		t.Errorf("%d icon servers started, want 1", started)
+	}
+}
+
+func TestIconServerIsBounded(t *testing.T) {
+	client := NewClient("Icons").WithIconMode(IconModeHttpURL)
+	defer client.Close()
+
+	err := client.WithIconServer(IconServerOptions{Addr: "127.0.0.1:0", AdvertisedHost: "127.0.0.1", MaxBytes: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	icon := func(b byte, size int) *Resource {
+		data := bytes.Repeat([]byte{b}, size)
+		return &Resource{Identifier: ResourceID(data), Data: data, MimeType: "image/png"}
+	}
+
+	first, err := client.iconReference(&Request{}, icon('a', 60))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fetchIcon(t, first); got != http.StatusOK {
+		t.Fatalf("GET %s = %d, want 200", first, got)
+	}
+
+	second, err := client.iconReference(&Request{}, icon('b', 60))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fetchIcon(t, second); got != http.StatusOK {
+		t.Errorf("GET %s = %d, want 200", second, got)
+	}
+	if got := fetchIcon(t, first); got != http.StatusNotFound {
+		t.Errorf("GET evicted %s = %d, want 404", first, got)
+	}
+	if n := client.servedIcons().icons.len(); n != 1 {
+		t.Errorf("server keeps %d icons, want 1", n)
+	}
+
+	large, err := client.iconReference(&Request{}, icon('c', 200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(large, "data:image/png;base64,") {
+		t.Errorf("icon larger than MaxBytes referenced as %q, want a data URL", large)
+	}
+}
+
+// fetchIcon requests url and returns the status code
+func fetchIcon(t *testing.T, url string) int {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode
+}
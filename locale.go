@@ -0,0 +1,494 @@
+package gntp
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// PluralCategory is a CLDR plural category
+type PluralCategory int
+
+const (
+	PluralOther PluralCategory = iota
+	PluralZero
+	PluralOne
+	PluralTwo
+	PluralFew
+	PluralMany
+)
+
+// PluralRule returns the plural category of a count
+type PluralRule func(n int64) PluralCategory
+
+// Catalog holds translated messages by locale. Keys are the untranslated
+// text: a display name, a template source or the argument of tr. Lookups
+// fall back from the most specific locale to its language, then to
+// DefaultLocale, so pt-BR falls back to pt.
+type Catalog struct {
+	DefaultLocale string
+
+	mu       sync.RWMutex
+	messages map[string]map[string]map[PluralCategory]string // By locale, key, category
+}
+
+// NewCatalog creates an empty catalog
+func NewCatalog(defaultLocale string) *Catalog {
+	return &Catalog{DefaultLocale: normalizeLocale(defaultLocale)}
+}
+
+// Set adds the translation of key for locale
+func (c *Catalog) Set(locale, key, message string) *Catalog {
+	return c.SetPlural(locale, key, map[PluralCategory]string{PluralOther: message})
+}
+
+// SetPlural adds the plural forms of key for locale. "{count}" in a form is
+// replaced by the count. PluralOther is used for categories without a form.
+func (c *Catalog) SetPlural(locale, key string, forms map[PluralCategory]string) *Catalog {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	locale = normalizeLocale(locale)
+	if c.messages == nil {
+		c.messages = make(map[string]map[string]map[PluralCategory]string)
+	}
+	if c.messages[locale] == nil {
+		c.messages[locale] = make(map[string]map[PluralCategory]string)
+	}
+
+	copied := make(map[PluralCategory]string, len(forms))
+	for category, form := range forms {
+		copied[category] = form
+	}
+	c.messages[locale][key] = copied
+	return c
+}
+
+// Lookup returns the translation of key for locale
+func (c *Catalog) Lookup(locale, key string) (string, bool) {
+	forms, _, ok := c.find(locale, key)
+	if !ok {
+		return "", false
+	}
+	message, ok := forms[PluralOther]
+	return message, ok
+}
+
+// Plural returns the form of key for count n in locale, with "{count}"
+// replaced by n
+func (c *Catalog) Plural(locale, key string, n int64) (string, bool) {
+	forms, found, ok := c.find(locale, key)
+	if !ok {
+		return "", false
+	}
+
+	message, ok := forms[pluralRule(found)(n)]
+	if !ok {
+		if message, ok = forms[PluralOther]; !ok {
+			return "", false
+		}
+	}
+	return strings.ReplaceAll(message, "{count}", strconv.FormatInt(n, 10)), true
+}
+
+// find returns the forms of key in the first locale of the fallback chain
+// that has them, and that locale
+func (c *Catalog) find(locale, key string) (map[PluralCategory]string, string, bool) {
+	if key == "" {
+		return nil, "", false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	chain := append(localeChain(locale), localeChain(c.DefaultLocale)...)
+	for _, candidate := range chain {
+		if forms, ok := c.messages[candidate][key]; ok {
+			return forms, candidate, true
+		}
+	}
+	return nil, "", false
+}
+
+// normalizeLocale converts a locale such as "pt_BR.UTF-8" to "pt-BR". It
+// returns "" for the C and POSIX locales.
+func normalizeLocale(locale string) string {
+	if i := strings.IndexAny(locale, ".@"); i >= 0 {
+		locale = locale[:i]
+	}
+	parts := strings.FieldsFunc(locale, func(r rune) bool {
+		return r == '_' || r == '-'
+	})
+	if len(parts) == 0 {
+		return ""
+	}
+
+	parts[0] = strings.ToLower(parts[0])
+	if parts[0] == "c" || parts[0] == "posix" {
+		return ""
+	}
+	for i := 1; i < len(parts); i++ {
+		switch len(parts[i]) {
+		case 2:
+			parts[i] = strings.ToUpper(parts[i]) // Region
+		case 4:
+			parts[i] = strings.ToUpper(parts[i][:1]) + strings.ToLower(parts[i][1:]) // Script
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// localeChain returns locale and its less specific forms: zh-Hant-TW,
+// zh-Hant, zh
+func localeChain(locale string) []string {
+	locale = normalizeLocale(locale)
+	var chain []string
+	for locale != "" {
+		chain = append(chain, locale)
+		i := strings.LastIndex(locale, "-")
+		if i < 0 {
+			break
+		}
+		locale = locale[:i]
+	}
+	return chain
+}
+
+// envLocale returns the locale from LC_ALL, LC_MESSAGES or LANG
+func envLocale() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			return normalizeLocale(value)
+		}
+	}
+	return ""
+}
+
+// pluralRules are the registered plural rules by locale
+var pluralRules = struct {
+	sync.RWMutex
+	byLocale map[string]PluralRule
+}{byLocale: make(map[string]PluralRule)}
+
+func init() {
+	for _, lang := range []string{"en", "de", "nl", "sv", "da", "nb", "nn", "no", "fi", "et", "it", "es", "ca", "el", "hu", "tr", "bg", "pt-PT"} {
+		RegisterPluralRule(lang, pluralOneOther)
+	}
+	for _, lang := range []string{"fr", "pt", "hi"} {
+		RegisterPluralRule(lang, pluralZeroOneOther)
+	}
+	for _, lang := range []string{"ja", "zh", "ko", "vi", "th", "id"} {
+		RegisterPluralRule(lang, func(int64) PluralCategory { return PluralOther })
+	}
+	for _, lang := range []string{"ru", "uk", "be"} {
+		RegisterPluralRule(lang, pluralEastSlavic)
+	}
+	for _, lang := range []string{"hr", "sr", "bs"} {
+		RegisterPluralRule(lang, pluralSerboCroatian)
+	}
+	RegisterPluralRule("pl", pluralPolish)
+	for _, lang := range []string{"cs", "sk"} {
+		RegisterPluralRule(lang, pluralCzech)
+	}
+}
+
+// RegisterPluralRule sets the plural rule of a language or locale, replacing
+// the built-in one
+func RegisterPluralRule(locale string, rule PluralRule) {
+	pluralRules.Lock()
+	defer pluralRules.Unlock()
+
+	pluralRules.byLocale[normalizeLocale(locale)] = rule
+}
+
+// pluralRule returns the rule of locale or its language. Languages without
+// a rule use "one" for 1 and "other" otherwise.
+func pluralRule(locale string) PluralRule {
+	pluralRules.RLock()
+	defer pluralRules.RUnlock()
+
+	for _, candidate := range localeChain(locale) {
+		if rule, ok := pluralRules.byLocale[candidate]; ok {
+			return rule
+		}
+	}
+	return pluralOneOther
+}
+
+func pluralOneOther(n int64) PluralCategory {
+	if n == 1 {
+		return PluralOne
+	}
+	return PluralOther
+}
+
+func pluralZeroOneOther(n int64) PluralCategory {
+	if n == 0 || n == 1 {
+		return PluralOne
+	}
+	return PluralOther
+}
+
+func pluralEastSlavic(n int64) PluralCategory {
+	switch mod10, mod100 := n%10, n%100; {
+	case mod10 == 1 && mod100 != 11:
+		return PluralOne
+	case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+		return PluralFew
+	default:
+		return PluralMany
+	}
+}
+
+func pluralSerboCroatian(n int64) PluralCategory {
+	if category := pluralEastSlavic(n); category != PluralMany {
+		return category
+	}
+	return PluralOther
+}
+
+func pluralPolish(n int64) PluralCategory {
+	if n == 1 {
+		return PluralOne
+	}
+	if category := pluralEastSlavic(n); category == PluralFew {
+		return PluralFew
+	}
+	return PluralMany
+}
+
+func pluralCzech(n int64) PluralCategory {
+	switch {
+	case n == 1:
+		return PluralOne
+	case n >= 2 && n <= 4:
+		return PluralFew
+	default:
+		return PluralOther
+	}
+}
+
+// WithCatalog translates display names and templates with catalog. The
+// translated templates of registered applications are parsed now; invalid
+// ones are logged and fail when rendered.
+func (c *Client) WithCatalog(catalog *Catalog) *Client {
+	c.mu.Lock()
+	c.catalog = catalog
+	c.mu.Unlock()
+
+	c.prepareTemplates()
+	return c
+}
+
+// WithLocale sets the locale of the destination, such as "pt-BR". Without
+// it the locale comes from LC_ALL, LC_MESSAGES or LANG.
+func (c *Client) WithLocale(locale string) *Client {
+	c.mu.Lock()
+	c.locale = normalizeLocale(locale)
+	c.mu.Unlock()
+
+	c.prepareTemplates()
+	return c
+}
+
+// prepareTemplates parses the translated templates of the registered
+// applications for the current locale
+func (c *Client) prepareTemplates() {
+	l := c.localizer()
+	if l == nil {
+		return
+	}
+
+	c.mu.Lock()
+	states := make([]*applicationState, 0, len(c.applications))
+	for _, state := range c.applications {
+		states = append(states, state)
+	}
+	c.mu.Unlock()
+
+	for _, state := range states {
+		if err := l.prepare(state); err != nil {
+			c.log().Warn("gntp translated templates are invalid",
+				slog.String("application", state.app.Name),
+				slog.String("locale", l.locale),
+				slog.Any("error", err),
+			)
+		}
+	}
+}
+
+// Locale returns the locale of the destination
+func (c *Client) Locale() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.locale != "" {
+		return c.locale
+	}
+	return envLocale()
+}
+
+// localizer translates for one locale
+type localizer struct {
+	catalog *Catalog
+	locale  string
+}
+
+// localizer returns the client's localizer, or nil without a catalog
+func (c *Client) localizer() *localizer {
+	c.mu.Lock()
+	catalog := c.catalog
+	c.mu.Unlock()
+
+	if catalog == nil {
+		return nil
+	}
+	return &localizer{catalog: catalog, locale: c.Locale()}
+}
+
+// translate is the tr template function: {{tr "Backup finished"}}. It
+// returns key if there is no translation.
+func (l *localizer) translate(key string) string {
+	if l == nil {
+		return key
+	}
+	if message, ok := l.catalog.Lookup(l.locale, key); ok {
+		return message
+	}
+	return key
+}
+
+// plural is the plural template function: {{plural "{count} files" .Count}}
+func (l *localizer) plural(key string, n any) (string, error) {
+	count, err := toInt64(n)
+	if err != nil {
+		return "", fmt.Errorf("plural: %w", err)
+	}
+	if l != nil {
+		if message, ok := l.catalog.Plural(l.locale, key, count); ok {
+			return message, nil
+		}
+	}
+	return strings.ReplaceAll(key, "{count}", strconv.FormatInt(count, 10)), nil
+}
+
+// funcs returns the template functions bound to the locale, or nil
+func (l *localizer) funcs() template.FuncMap {
+	if l == nil {
+		return nil
+	}
+	return template.FuncMap{
+		"tr":     l.translate,
+		"plural": l.plural,
+	}
+}
+
+// displayName returns the translated display name of a notification type,
+// looked up by its display name or, without one, its name
+func (l *localizer) displayName(nt *NotificationType) string {
+	if l == nil {
+		return nt.DisplayName
+	}
+
+	key := nt.DisplayName
+	if key == "" {
+		key = nt.Name
+	}
+	if message, ok := l.catalog.Lookup(l.locale, key); ok {
+		return message
+	}
+	return nt.DisplayName
+}
+
+// localizedTemplates are templates parsed from translated sources
+type localizedTemplates struct {
+	title, text string // Translated sources
+	templates   *notificationTemplates
+}
+
+// templates returns the templates of nt translated for the locale, or the
+// untranslated ones in state if the catalog translates neither source.
+// Translated templates are parsed once per locale and cached in state until
+// the catalog changes their sources.
+func (l *localizer) templates(state *applicationState, nt *NotificationType) (*notificationTemplates, error) {
+	if nt == nil {
+		return nil, nil
+	}
+	parsed := state.templates[nt.Name]
+	if l == nil {
+		return parsed, nil
+	}
+
+	title, titleOK := l.catalog.Lookup(l.locale, nt.TitleTemplate)
+	text, textOK := l.catalog.Lookup(l.locale, nt.TextTemplate)
+	if !titleOK && !textOK {
+		return parsed, nil
+	}
+	if !titleOK {
+		title = nt.TitleTemplate
+	}
+	if !textOK {
+		text = nt.TextTemplate
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if cached, ok := state.localized[l.locale][nt.Name]; ok && cached.title == title && cached.text == text {
+		return cached.templates, nil
+	}
+
+	localized := *nt
+	localized.TitleTemplate = title
+	localized.TextTemplate = text
+	templates, err := localized.parseTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	if state.localized == nil {
+		state.localized = make(map[string]map[string]*localizedTemplates)
+	}
+	if state.localized[l.locale] == nil {
+		state.localized[l.locale] = make(map[string]*localizedTemplates)
+	}
+	state.localized[l.locale][nt.Name] = &localizedTemplates{title: title, text: text, templates: templates}
+	return templates, nil
+}
+
+// prepare parses and caches the translated templates of every notification
+// type in state, returning a ValidationError for those that are invalid
+func (l *localizer) prepare(state *applicationState) error {
+	if l == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, nt := range state.notifications {
+		if _, err := l.templates(state, nt); err != nil {
+			for _, problem := range problems(err) {
+				errs = append(errs, fmt.Errorf("%s: %w", l.locale, problem))
+			}
+		}
+	}
+	return validationError(errs)
+}
+
+// toInt64 converts any integer or float to int64
+func toInt64(n any) (int64, error) {
+	switch v := reflect.ValueOf(n); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return int64(v.Float()), nil
+	default:
+		return 0, fmt.Errorf("not a number: %T", n)
+	}
+}
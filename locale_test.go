@@ -0,0 +1,64 @@
+package gntp
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRegisterRejectsInvalidTranslatedTemplates(t *testing.T) {
+	server := newFakeServer(t)
+	catalog := NewCatalog("en").Set("pt-BR", "{{.Name}} finished", "{{.Name terminou")
+	client := server.client("Locale").WithCatalog(catalog).WithLocale("pt-BR")
+
+	err := client.Register([]*NotificationType{NewNotificationType("job").WithTemplates("{{.Name}} finished", "")})
+	if !errors.Is(err, ErrTemplate) {
+		t.Errorf("Register = %v, want ErrTemplate", err)
+	}
+	if got := len(server.received("REGISTER")); got != 0 {
+		t.Errorf("sent %d REGISTER requests with an invalid translation", got)
+	}
+}
+
+func TestTranslatedTemplatesAreCached(t *testing.T) {
+	server := newFakeServer(t)
+	catalog := NewCatalog("en").Set("pt-BR", "{{.Name}} finished", "{{.Name}} terminou")
+	client := server.client("Locale").WithCatalog(catalog).WithLocale("en")
+
+	job := NewNotificationType("job").WithTemplates("{{.Name}} finished", "")
+	if err := client.Register([]*NotificationType{job}); err != nil {
+		t.Fatal(err)
+	}
+	state := client.registered("Locale")
+	if len(state.localized) != 0 {
+		t.Errorf("cached %d locales without a translation", len(state.localized))
+	}
+
+	client.WithLocale("pt-BR")
+	cached := state.localized["pt-BR"]["job"]
+	if cached == nil {
+		t.Fatal("WithLocale did not parse the translated templates")
+	}
+
+	data := struct{ Name string }{"Backup"}
+	if err := client.NotifyData("job", data); err != nil {
+		t.Fatal(err)
+	}
+	if state.localized["pt-BR"]["job"] != cached {
+		t.Error("NotifyData parsed the translated templates again")
+	}
+
+	catalog.Set("pt-BR", "{{.Name}} finished", "{{.Name}} concluído")
+	if err := client.NotifyData("job", data); err != nil {
+		t.Fatal(err)
+	}
+
+	notify := server.received("NOTIFY")
+	if len(notify) != 2 {
+		t.Fatalf("received %d notifications, want 2", len(notify))
+	}
+	for i, want := range []string{"Backup terminou", "Backup concluído"} {
+		if got := notify[i].Headers["Notification-Title"]; got != want {
+			t.Errorf("notification %d title = %q, want %q", i, got, want)
+		}
+	}
+}
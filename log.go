@@ -0,0 +1,83 @@
+package gntp
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// maxLoggedValue is the length after which header values are truncated in logs
+const maxLoggedValue = 64
+
+// discardLogger is used when neither a logger nor debug mode is set
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// WithLogger sets the logger used for diagnostics. Requests are logged at
+// debug level, failures at warn level. Icon data is truncated and the
+// password key hash is never logged.
+func (c *Client) WithLogger(logger *slog.Logger) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.logger = logger
+	return c
+}
+
+// log returns the logger for diagnostics. Without WithLogger, debug mode
+// logs to stdout and otherwise nothing is logged.
+func (c *Client) log() *slog.Logger {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.logger != nil {
+		return c.logger
+	}
+	if !c.Debug {
+		return discardLogger
+	}
+	if c.debugLogger == nil {
+		c.debugLogger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+	return c.debugLogger
+}
+
+// redactPacket makes a packet safe and readable for logs: data URLs and
+// long values are truncated and the key hash of the information line is
+// removed
+func redactPacket(packet string) string {
+	lines := strings.Split(packet, CRLF)
+
+	for i, line := range lines {
+		if i == 0 {
+			// GNTP/1.0 NOTIFY NONE [MD5:keyhash.salt]
+			fields := strings.Fields(line)
+			if len(fields) > 3 {
+				fields = append(fields[:3], "<redacted>")
+			}
+			lines[i] = strings.Join(fields, " ")
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		lines[i] = name + ": " + redactValue(value)
+	}
+
+	return strings.Join(lines, CRLF)
+}
+
+// redactValue truncates data URLs and overly long header values
+func redactValue(value string) string {
+	if strings.HasPrefix(value, "data:") {
+		if prefix, data, ok := strings.Cut(value, ","); ok {
+			return fmt.Sprintf("%s,<%d bytes>", prefix, len(data))
+		}
+	}
+	if len(value) > maxLoggedValue {
+		return fmt.Sprintf("%s...<%d bytes>", value[:maxLoggedValue], len(value))
+	}
+	return value
+}
@@ -0,0 +1,87 @@
+package gntp
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lru is a least recently used cache bounded by the total size of its
+// values, safe for concurrent use
+type lru[K comparable, V any] struct {
+	mu      sync.Mutex
+	maxSize int64
+	size    int64
+	sizeOf  func(V) int64
+	order   *list.List // Most recently used first
+	entries map[K]*list.Element
+}
+
+// lruEntry is a value in an lru
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+	size  int64
+}
+
+// newLRU creates a cache holding values of up to maxSize in total
+func newLRU[K comparable, V any](maxSize int64, sizeOf func(V) int64) *lru[K, V] {
+	return &lru[K, V]{
+		maxSize: maxSize,
+		sizeOf:  sizeOf,
+		order:   list.New(),
+		entries: make(map[K]*list.Element),
+	}
+}
+
+// get returns the value of key and marks it as recently used
+func (c *lru[K, V]) get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry[K, V]).value, true
+}
+
+// add stores value under key, evicting the least recently used values to
+// stay within the size limit. A value larger than the limit is not stored
+// and add returns false.
+func (c *lru[K, V]) add(key K, value V) bool {
+	size := c.sizeOf(value)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+	if size > c.maxSize {
+		return false
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value, size: size})
+	c.size += size
+	for c.size > c.maxSize {
+		c.remove(c.order.Back())
+	}
+	return true
+}
+
+// len returns the number of values
+func (c *lru[K, V]) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+// remove drops an element; c.mu must be held
+func (c *lru[K, V]) remove(elem *list.Element) {
+	entry := c.order.Remove(elem).(*lruEntry[K, V])
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+}
@@ -0,0 +1,330 @@
+package gntp
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, of the latency histograms
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics receives instrumentation events from the send path and the
+// callback listener. Implementations must be safe for concurrent use.
+type Metrics interface {
+	// RequestStarted is called before a request is sent
+	RequestStarted(request RequestType)
+
+	// RequestSucceeded is called when the server accepted a request
+	RequestSucceeded(request RequestType, duration time.Duration, bytes int)
+
+	// RequestFailed is called when a request failed. code is the GNTP error
+	// code, or 0 if the server could not be reached.
+	RequestFailed(request RequestType, code int, duration time.Duration)
+
+	// CallbackReceived is called for every callback from the server
+	CallbackReceived(callback CallbackType)
+}
+
+// WithMetrics sets the instrumentation called for every request and callback
+func (c *Client) WithMetrics(metrics Metrics) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.metrics = metrics
+	return c
+}
+
+// instruments returns the metrics set with WithMetrics, or nil
+func (c *Client) instruments() Metrics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.metrics
+}
+
+// histogram counts observations into cumulative buckets
+type histogram struct {
+	buckets []float64
+	counts  []uint64 // counts[i] is the number of observations <= buckets[i]
+	sum     float64
+	count   uint64
+}
+
+// newHistogram creates an empty histogram with the default buckets
+func newHistogram() *histogram {
+	return &histogram{
+		buckets: DefaultLatencyBuckets,
+		counts:  make([]uint64, len(DefaultLatencyBuckets)),
+	}
+}
+
+// observe records a value
+func (h *histogram) observe(v float64) {
+	for i, bound := range h.buckets {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// formatFloat formats a float like the Prometheus text format does
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// ExpvarMetrics publishes counters as an expvar map, visible at /debug/vars.
+// Failures are keyed by request type and error code, and latency histograms
+// by request type.
+type ExpvarMetrics struct {
+	requests  *expvar.Map
+	successes *expvar.Map
+	failures  *expvar.Map
+	callbacks *expvar.Map
+	bytesSent *expvar.Int
+
+	mu      sync.Mutex
+	latency map[RequestType]*histogram
+}
+
+// NewExpvarMetrics publishes the metrics under name. Calling it again with
+// the same name replaces the counters of the earlier instance.
+func NewExpvarMetrics(name string) *ExpvarMetrics {
+	root, ok := expvar.Get(name).(*expvar.Map)
+	if !ok {
+		root = expvar.NewMap(name)
+	}
+
+	m := &ExpvarMetrics{
+		requests:  new(expvar.Map).Init(),
+		successes: new(expvar.Map).Init(),
+		failures:  new(expvar.Map).Init(),
+		callbacks: new(expvar.Map).Init(),
+		bytesSent: new(expvar.Int),
+		latency:   make(map[RequestType]*histogram),
+	}
+
+	root.Set("requests", m.requests)
+	root.Set("successes", m.successes)
+	root.Set("failures", m.failures)
+	root.Set("callbacks", m.callbacks)
+	root.Set("bytes_sent", m.bytesSent)
+	root.Set("latency_seconds", expvar.Func(m.latencySnapshot))
+	return m
+}
+
+// RequestStarted counts the request
+func (m *ExpvarMetrics) RequestStarted(request RequestType) {
+	m.requests.Add(string(request), 1)
+}
+
+// RequestSucceeded counts the success, its bytes and latency
+func (m *ExpvarMetrics) RequestSucceeded(request RequestType, duration time.Duration, bytes int) {
+	m.successes.Add(string(request), 1)
+	m.bytesSent.Add(int64(bytes))
+	m.observe(request, duration)
+}
+
+// RequestFailed counts the failure by request type and error code, keyed
+// like "NOTIFY/402"
+func (m *ExpvarMetrics) RequestFailed(request RequestType, code int, duration time.Duration) {
+	m.failures.Add(string(request)+"/"+strconv.Itoa(code), 1)
+	m.observe(request, duration)
+}
+
+// CallbackReceived counts the callback by type
+func (m *ExpvarMetrics) CallbackReceived(callback CallbackType) {
+	m.callbacks.Add(string(callback), 1)
+}
+
+// observe records a latency
+func (m *ExpvarMetrics) observe(request RequestType, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, ok := m.latency[request]
+	if !ok {
+		h = newHistogram()
+		m.latency[request] = h
+	}
+	h.observe(duration.Seconds())
+}
+
+// latencySnapshot returns the latency histograms by request type for expvar
+func (m *ExpvarMetrics) latencySnapshot() any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make(map[string]any, len(m.latency))
+	for request, h := range m.latency {
+		buckets := make(map[string]uint64, len(h.buckets)+1)
+		for i, bound := range h.buckets {
+			buckets[formatFloat(bound)] = h.counts[i]
+		}
+		buckets["+Inf"] = h.count
+
+		snapshot[string(request)] = map[string]any{
+			"buckets": buckets,
+			"sum":     h.sum,
+			"count":   h.count,
+		}
+	}
+	return snapshot
+}
+
+// PrometheusMetrics collects metrics and serves them in the Prometheus text
+// exposition format. Mount it on a mux, e.g. at /metrics.
+type PrometheusMetrics struct {
+	mu        sync.Mutex
+	requests  map[RequestType]uint64
+	successes map[RequestType]uint64
+	failures  map[failureKey]uint64
+	callbacks map[CallbackType]uint64
+	bytesSent uint64
+	latency   map[RequestType]*histogram
+}
+
+// failureKey labels a failure counter
+type failureKey struct {
+	request RequestType
+	code    int
+}
+
+// NewPrometheusMetrics creates an empty collector
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return &PrometheusMetrics{
+		requests:  make(map[RequestType]uint64),
+		successes: make(map[RequestType]uint64),
+		failures:  make(map[failureKey]uint64),
+		callbacks: make(map[CallbackType]uint64),
+		latency:   make(map[RequestType]*histogram),
+	}
+}
+
+// RequestStarted counts the request
+func (m *PrometheusMetrics) RequestStarted(request RequestType) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[request]++
+}
+
+// RequestSucceeded counts the success, its bytes and latency
+func (m *PrometheusMetrics) RequestSucceeded(request RequestType, duration time.Duration, bytes int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.successes[request]++
+	m.bytesSent += uint64(bytes)
+	m.observe(request, duration)
+}
+
+// RequestFailed counts the failure by error code
+func (m *PrometheusMetrics) RequestFailed(request RequestType, code int, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.failures[failureKey{request: request, code: code}]++
+	m.observe(request, duration)
+}
+
+// CallbackReceived counts the callback by type
+func (m *PrometheusMetrics) CallbackReceived(callback CallbackType) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.callbacks[callback]++
+}
+
+// observe records a latency; m.mu must be held
+func (m *PrometheusMetrics) observe(request RequestType, duration time.Duration) {
+	h, ok := m.latency[request]
+	if !ok {
+		h = newHistogram()
+		m.latency[request] = h
+	}
+	h.observe(duration.Seconds())
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	fmt.Fprint(w, m.String())
+}
+
+// String returns the metrics in the Prometheus text format
+func (m *PrometheusMetrics) String() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var sb strings.Builder
+
+	writeMetricHeader(&sb, "gntp_requests_total", "counter", "GNTP requests sent.")
+	for _, request := range sortedKeys(m.requests) {
+		fmt.Fprintf(&sb, "gntp_requests_total{type=%q} %d\n", request, m.requests[request])
+	}
+
+	writeMetricHeader(&sb, "gntp_request_successes_total", "counter", "GNTP requests accepted by the server.")
+	for _, request := range sortedKeys(m.successes) {
+		fmt.Fprintf(&sb, "gntp_request_successes_total{type=%q} %d\n", request, m.successes[request])
+	}
+
+	writeMetricHeader(&sb, "gntp_request_failures_total", "counter", "GNTP requests that failed, by error code (0 if the server was unreachable).")
+	failures := make([]failureKey, 0, len(m.failures))
+	for key := range m.failures {
+		failures = append(failures, key)
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		if failures[i].request != failures[j].request {
+			return failures[i].request < failures[j].request
+		}
+		return failures[i].code < failures[j].code
+	})
+	for _, key := range failures {
+		fmt.Fprintf(&sb, "gntp_request_failures_total{type=%q,code=\"%d\"} %d\n", key.request, key.code, m.failures[key])
+	}
+
+	writeMetricHeader(&sb, "gntp_request_duration_seconds", "histogram", "GNTP request latency.")
+	for _, request := range sortedKeys(m.latency) {
+		h := m.latency[request]
+		for i, bound := range h.buckets {
+			fmt.Fprintf(&sb, "gntp_request_duration_seconds_bucket{type=%q,le=%q} %d\n", request, formatFloat(bound), h.counts[i])
+		}
+		fmt.Fprintf(&sb, "gntp_request_duration_seconds_bucket{type=%q,le=\"+Inf\"} %d\n", request, h.count)
+		fmt.Fprintf(&sb, "gntp_request_duration_seconds_sum{type=%q} %s\n", request, formatFloat(h.sum))
+		fmt.Fprintf(&sb, "gntp_request_duration_seconds_count{type=%q} %d\n", request, h.count)
+	}
+
+	writeMetricHeader(&sb, "gntp_bytes_sent_total", "counter", "Bytes sent in successful GNTP requests.")
+	fmt.Fprintf(&sb, "gntp_bytes_sent_total %d\n", m.bytesSent)
+
+	writeMetricHeader(&sb, "gntp_callbacks_total", "counter", "GNTP callbacks received, by type.")
+	for _, callback := range sortedKeys(m.callbacks) {
+		fmt.Fprintf(&sb, "gntp_callbacks_total{type=%q} %d\n", callback, m.callbacks[callback])
+	}
+
+	return sb.String()
+}
+
+// writeMetricHeader writes the HELP and TYPE lines of a metric
+func writeMetricHeader(sb *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(sb, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(sb, "# TYPE %s %s\n", name, kind)
+}
+
+// sortedKeys returns the keys of a string-keyed map in order
+func sortedKeys[K ~string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
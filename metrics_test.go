@@ -0,0 +1,81 @@
+package gntp
+
+import (
+	"encoding/json"
+	"expvar"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	m := NewPrometheusMetrics()
+	m.RequestStarted(RequestRegister)
+	m.RequestSucceeded(RequestRegister, 20*time.Millisecond, 300)
+	m.RequestStarted(RequestNotify)
+	m.RequestStarted(RequestNotify)
+	m.RequestSucceeded(RequestNotify, 3*time.Millisecond, 200)
+	m.RequestFailed(RequestNotify, ErrorUnknownNotification, 2*time.Second)
+	m.CallbackReceived(CallbackClick)
+
+	got := m.String()
+	for _, want := range []string{
+		"# TYPE gntp_requests_total counter\n",
+		`gntp_requests_total{type="NOTIFY"} 2` + "\n",
+		`gntp_requests_total{type="REGISTER"} 1` + "\n",
+		`gntp_request_successes_total{type="NOTIFY"} 1` + "\n",
+		`gntp_request_failures_total{type="NOTIFY",code="402"} 1` + "\n",
+		"# TYPE gntp_request_duration_seconds histogram\n",
+		`gntp_request_duration_seconds_bucket{type="NOTIFY",le="0.005"} 1` + "\n",
+		`gntp_request_duration_seconds_bucket{type="NOTIFY",le="2.5"} 2` + "\n",
+		`gntp_request_duration_seconds_bucket{type="NOTIFY",le="+Inf"} 2` + "\n",
+		`gntp_request_duration_seconds_sum{type="NOTIFY"} 2.003` + "\n",
+		`gntp_request_duration_seconds_count{type="NOTIFY"} 2` + "\n",
+		`gntp_request_duration_seconds_bucket{type="REGISTER",le="0.01"} 0` + "\n",
+		`gntp_request_duration_seconds_bucket{type="REGISTER",le="0.025"} 1` + "\n",
+		"gntp_bytes_sent_total 500\n",
+		`gntp_callbacks_total{type="` + string(CallbackClick) + `"} 1` + "\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output lacks %q:\n%s", want, got)
+		}
+	}
+
+	// Every sample line follows the HELP and TYPE of its metric
+	for _, line := range strings.Split(strings.TrimSpace(got), "\n") {
+		if !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "gntp_") {
+			t.Errorf("malformed line %q", line)
+		}
+	}
+}
+
+func TestExpvarMetricsByRequestType(t *testing.T) {
+	m := NewExpvarMetrics("gntp_test")
+	m.RequestFailed(RequestRegister, ErrorNotAuthorized, 10*time.Millisecond)
+	m.RequestFailed(RequestNotify, ErrorNotAuthorized, 10*time.Millisecond)
+	m.RequestFailed(RequestNotify, ErrorNotAuthorized, 10*time.Millisecond)
+	m.RequestSucceeded(RequestNotify, time.Millisecond, 100)
+
+	var vars struct {
+		Failures map[string]int `json:"failures"`
+		Latency  map[string]struct {
+			Count uint64 `json:"count"`
+		} `json:"latency_seconds"`
+	}
+	if err := json.Unmarshal([]byte(expvar.Get("gntp_test").String()), &vars); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := vars.Failures["REGISTER/400"]; got != 1 {
+		t.Errorf("REGISTER/400 = %d, want 1", got)
+	}
+	if got := vars.Failures["NOTIFY/400"]; got != 2 {
+		t.Errorf("NOTIFY/400 = %d, want 2", got)
+	}
+	if got := vars.Latency["NOTIFY"].Count; got != 3 {
+		t.Errorf("NOTIFY latency count = %d, want 3", got)
+	}
+	if got := vars.Latency["REGISTER"].Count; got != 1 {
+		t.Errorf("REGISTER latency count = %d, want 1", got)
+	}
+}
@@ -0,0 +1,166 @@
+package gntp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Notifier registers notification types and sends notifications.
+// Client implements it; application code written against Notifier can use
+// RecordingNotifier in tests, NopNotifier to disable notifications, or
+// MultiNotifier to deliver to several destinations.
+type Notifier interface {
+	RegisterContext(ctx context.Context, notifications []*NotificationType) error
+	NotifyContext(ctx context.Context, notificationName, title, text string, options *NotifyOptions) error
+}
+
+var (
+	_ Notifier = (*Client)(nil)
+	_ Notifier = (*Digest)(nil)
+	_ Notifier = (*RecordingNotifier)(nil)
+	_ Notifier = NopNotifier{}
+)
+
+// RegisterContext registers the notification types with the wrapped client
+func (d *Digest) RegisterContext(ctx context.Context, notifications []*NotificationType) error {
+	return d.client.RegisterContext(ctx, notifications)
+}
+
+// NotifyContext buffers a notification with options. The context is not
+// used because the summary is sent later.
+func (d *Digest) NotifyContext(ctx context.Context, notificationName, title, text string, options *NotifyOptions) error {
+	return d.NotifyWithOptions(notificationName, title, text, options)
+}
+
+// RecordedNotification is a notification captured by RecordingNotifier
+type RecordedNotification struct {
+	NotificationName string
+	Title            string
+	Text             string
+	Options          NotifyOptions
+	Timestamp        time.Time
+}
+
+// RecordingNotifier is an in-memory Notifier that records every call
+type RecordingNotifier struct {
+	// Err, if set, is returned by every call after it is recorded
+	Err error
+
+	mu            sync.Mutex
+	registrations [][]*NotificationType
+	notifications []RecordedNotification
+}
+
+// NewRecordingNotifier creates an empty RecordingNotifier
+func NewRecordingNotifier() *RecordingNotifier {
+	return &RecordingNotifier{}
+}
+
+// RegisterContext records the notification types
+func (r *RecordingNotifier) RegisterContext(ctx context.Context, notifications []*NotificationType) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	types := make([]*NotificationType, len(notifications))
+	copy(types, notifications)
+	r.registrations = append(r.registrations, types)
+	return r.Err
+}
+
+// NotifyContext records the notification
+func (r *RecordingNotifier) NotifyContext(ctx context.Context, notificationName, title, text string, options *NotifyOptions) error {
+	if options == nil {
+		options = NewNotifyOptions()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.notifications = append(r.notifications, RecordedNotification{
+		NotificationName: notificationName,
+		Title:            title,
+		Text:             text,
+		Options:          *options,
+		Timestamp:        time.Now(),
+	})
+	return r.Err
+}
+
+// Registrations returns the notification types of every RegisterContext call
+func (r *RecordingNotifier) Registrations() [][]*NotificationType {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	registrations := make([][]*NotificationType, len(r.registrations))
+	copy(registrations, r.registrations)
+	return registrations
+}
+
+// Notifications returns the recorded notifications in the order they were sent
+func (r *RecordingNotifier) Notifications() []RecordedNotification {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notifications := make([]RecordedNotification, len(r.notifications))
+	copy(notifications, r.notifications)
+	return notifications
+}
+
+// Reset discards everything recorded so far
+func (r *RecordingNotifier) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.registrations = nil
+	r.notifications = nil
+}
+
+// NopNotifier is a Notifier that discards everything
+type NopNotifier struct{}
+
+// RegisterContext does nothing
+func (NopNotifier) RegisterContext(ctx context.Context, notifications []*NotificationType) error {
+	return nil
+}
+
+// NotifyContext does nothing
+func (NopNotifier) NotifyContext(ctx context.Context, notificationName, title, text string, options *NotifyOptions) error {
+	return nil
+}
+
+// multiNotifier delivers to several notifiers
+type multiNotifier struct {
+	notifiers []Notifier
+}
+
+// MultiNotifier returns a Notifier that forwards every call to all notifiers.
+// Every notifier is called even if an earlier one fails; the errors are joined.
+func MultiNotifier(notifiers ...Notifier) Notifier {
+	all := make([]Notifier, len(notifiers))
+	copy(all, notifiers)
+	return &multiNotifier{notifiers: all}
+}
+
+// RegisterContext registers with every notifier
+func (m *multiNotifier) RegisterContext(ctx context.Context, notifications []*NotificationType) error {
+	var errs []error
+	for _, n := range m.notifiers {
+		if err := n.RegisterContext(ctx, notifications); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NotifyContext notifies every notifier
+func (m *multiNotifier) NotifyContext(ctx context.Context, notificationName, title, text string, options *NotifyOptions) error {
+	var errs []error
+	for _, n := range m.notifiers {
+		if err := n.NotifyContext(ctx, notificationName, title, text, options); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,65 @@
+package gntp
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotRegistered is returned by Probe before the application is registered
+var ErrNotRegistered = errors.New("gntp: application not registered")
+
+// ProbeResult describes a destination as seen by Probe
+type ProbeResult struct {
+	Reachable        bool          // The server answered with a GNTP response
+	Latency          time.Duration // Round trip of the probe
+	Server           *ServerInfo   // Identity from Origin- headers, nil if not sent
+	PasswordRequired bool          // The server rejected the probe as not authorized
+	Profile          *Profile      // Profile matching the server's software name, nil if none matches
+	Error            error         // Server error returned by the probe, if any
+}
+
+// Probe checks the current destination with a REGISTER request. It re-sends
+// the application and notification types of the last Register call, so it
+// does not change the registration, and fails with ErrNotRegistered before
+// the first Register. The result's Profile is the one the client would
+// select for the server, even if WithProfile overrides it. Probe returns an
+// error only if the server could not be reached.
+func (c *Client) Probe(ctx context.Context) (*ProbeResult, error) {
+	state := c.registered(c.ApplicationName)
+	if state == nil {
+		return nil, ErrNotRegistered
+	}
+	app := state.app
+	app.Notifications = state.notifications
+	if err := app.Validate(); err != nil {
+		return nil, err
+	}
+
+	req, err := c.buildRegister(&app)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	_, err = c.send(ctx, req)
+	result := &ProbeResult{Latency: time.Since(start)}
+
+	var serverErr *ServerError
+	if err != nil && !errors.As(err, &serverErr) {
+		return result, err
+	}
+
+	result.Reachable = true
+	result.Server = c.ServerInfo()
+	if result.Server != nil {
+		if p, ok := LookupProfile(result.Server.SoftwareName); ok {
+			result.Profile = &p
+		}
+	}
+	if serverErr != nil {
+		result.Error = serverErr
+		result.PasswordRequired = serverErr.Code == ErrorNotAuthorized
+	}
+	return result, nil
+}
@@ -0,0 +1,57 @@
+package gntp
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestProbeRequiresRegister(t *testing.T) {
+	server := newFakeServer(t)
+	client := server.client("Probe")
+
+	if _, err := client.Probe(context.Background()); !errors.Is(err, ErrNotRegistered) {
+		t.Errorf("Probe before Register = %v, want ErrNotRegistered", err)
+	}
+	if got := len(server.received("REGISTER")); got != 0 {
+		t.Errorf("Probe before Register sent %d requests", got)
+	}
+}
+
+func TestProbeResendsRegistration(t *testing.T) {
+	server := newFakeServer(t)
+	server.setRespond(func(req *fakeRequest) string {
+		return "GNTP/1.0 -OK NONE\r\nResponse-Action: REGISTER\r\nOrigin-Software-Name: Snarl\r\n\r\n"
+	})
+	client := server.client("Probe")
+	if err := client.Register([]*NotificationType{NewNotificationType("a"), NewNotificationType("b")}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := client.Probe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Reachable {
+		t.Error("Reachable = false")
+	}
+	if result.Profile == nil || result.Profile.Name != ProfileSnarl.Name {
+		t.Errorf("Profile = %v, want Snarl", result.Profile)
+	}
+
+	registers := server.received("REGISTER")
+	if len(registers) != 2 {
+		t.Fatalf("received %d REGISTER requests, want 2", len(registers))
+	}
+	if got := len(registers[1].Sections); got != 2 {
+		t.Errorf("probe registered %d notification types, want 2", got)
+	}
+
+	client.WithProfile(ProfileGrowlWindows)
+	if result, err = client.Probe(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if result.Profile == nil || result.Profile.Name != ProfileSnarl.Name {
+		t.Errorf("Profile = %v with WithProfile, want the detected Snarl", result.Profile)
+	}
+}
@@ -0,0 +1,234 @@
+package gntp
+
+import (
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// CallbackStyle describes how a server delivers callbacks
+type CallbackStyle int
+
+const (
+	// CallbackTargetURL delivers callbacks to the listener started by
+	// WithCallback, via the Notification-Callback-Target header
+	CallbackTargetURL CallbackStyle = iota
+
+	// CallbackNone means the server does not deliver callbacks. Callback
+	// headers are omitted; an explicit CallbackTarget URL is still sent.
+	CallbackNone
+)
+
+// LineEnding describes how line breaks in titles and texts are sent
+type LineEnding int
+
+const (
+	// LineEndingLF sends line breaks as LF. GNTP header values may not
+	// contain CR, so CRLF and CR are always converted.
+	LineEndingLF LineEnding = iota
+
+	// LineEndingSpace replaces line breaks with spaces, for servers that
+	// display only one line
+	LineEndingSpace
+)
+
+// truncationMarker ends titles and texts shortened to a profile's limits
+const truncationMarker = "…"
+
+// Profile describes the quirks of a GNTP server. Predefined profiles are
+// selected automatically from the Origin-Software-Name of responses, or
+// explicitly with WithProfile.
+type Profile struct {
+	Name          string   // Human-readable name
+	SoftwareNames []string // Origin-Software-Name values matched, case-insensitively
+
+	IconMode       IconMode        // Icon mode applied by WithProfile; IconModeAuto also uses it when binary
+	IconProcessing *IconProcessing // Icon limits, unless the client sets its own
+
+	// Longer titles and texts are rejected with ErrTooLong when the profile
+	// is set with WithProfile, and truncated when it was detected. 0 is
+	// unlimited.
+	MaxTitleLength int
+	MaxTextLength  int
+
+	Sticky     bool // Notification-Sticky is honored
+	Priority   bool // Notification-Priority is honored
+	Coalescing bool // Notification-Coalescing-ID is honored
+
+	Callbacks  CallbackStyle
+	LineEnding LineEnding
+}
+
+// Predefined profiles. They choose how icons are delivered and otherwise
+// honor every header without length limits.
+var (
+	// ProfileGrowlWindows is Growl for Windows. Binary resources are the
+	// most reliable icon delivery; large data URLs fail.
+	ProfileGrowlWindows = Profile{
+		Name:          "Growl for Windows",
+		SoftwareNames: []string{"Growl/Win", "Growl for Windows"},
+		IconMode:      IconModeBinary,
+		Sticky:        true,
+		Priority:      true,
+		Coalescing:    true,
+	}
+
+	// ProfileGrowlMac is Growl for macOS
+	ProfileGrowlMac = Profile{
+		Name:          "Growl for Mac",
+		SoftwareNames: []string{"Growl"},
+		IconMode:      IconModeBinary,
+		Sticky:        true,
+		Priority:      true,
+		Coalescing:    true,
+	}
+
+	// ProfileGrowlAndroid is Growl for Android. File URLs don't resolve on
+	// the device and large data URLs fail, so icons are sent as small data
+	// URLs.
+	ProfileGrowlAndroid = Profile{
+		Name:           "Growl for Android",
+		SoftwareNames:  []string{"Growl for Android"},
+		IconMode:       IconModeDataURL,
+		IconProcessing: &IconProcessing{MaxDimension: 128, MaxBytes: 48 << 10},
+		Sticky:         true,
+		Priority:       true,
+		Coalescing:     true,
+	}
+
+	// ProfileSnarl is Snarl for Windows
+	ProfileSnarl = Profile{
+		Name:          "Snarl",
+		SoftwareNames: []string{"Snarl"},
+		IconMode:      IconModeBinary,
+		Sticky:        true,
+		Priority:      true,
+		Coalescing:    true,
+	}
+)
+
+// profiles are the registered profiles by lowercased software name
+var profiles = struct {
+	sync.RWMutex
+	bySoftware map[string]Profile
+}{bySoftware: make(map[string]Profile)}
+
+func init() {
+	for _, p := range []Profile{ProfileGrowlWindows, ProfileGrowlMac, ProfileGrowlAndroid, ProfileSnarl} {
+		RegisterProfile(p)
+	}
+}
+
+// RegisterProfile makes p the profile for servers reporting one of its
+// SoftwareNames, replacing any earlier profile for those names
+func RegisterProfile(p Profile) {
+	profiles.Lock()
+	defer profiles.Unlock()
+
+	for _, name := range p.SoftwareNames {
+		profiles.bySoftware[strings.ToLower(name)] = p
+	}
+}
+
+// LookupProfile returns the profile registered for a server's
+// Origin-Software-Name
+func LookupProfile(softwareName string) (Profile, bool) {
+	profiles.RLock()
+	defer profiles.RUnlock()
+
+	p, ok := profiles.bySoftware[strings.ToLower(softwareName)]
+	return p, ok
+}
+
+// WithProfile uses p for every destination instead of detecting the profile
+// from server responses, and applies its icon mode
+func (c *Client) WithProfile(p Profile) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.fixedProfile = &p
+	c.IconMode = p.IconMode
+	return c
+}
+
+// Profile returns the profile in effect for the current destination: the
+// one set with WithProfile, or the one matching the server's
+// Origin-Software-Name. It returns nil if neither is known.
+func (c *Client) Profile() *Profile {
+	p := c.profile()
+	if p == nil {
+		return nil
+	}
+	copied := *p
+	return &copied
+}
+
+// profile returns the profile in effect, or nil
+func (c *Client) profile() *Profile {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.fixedProfile != nil {
+		return c.fixedProfile
+	}
+	if state, ok := c.destinations[c.address()]; ok && state.info != nil {
+		if p, ok := LookupProfile(state.info.SoftwareName); ok {
+			return &p
+		}
+	}
+	return nil
+}
+
+// callbacks reports whether the server delivers callbacks
+func (p *Profile) callbacks() bool {
+	return p == nil || p.Callbacks != CallbackNone
+}
+
+// formatTitle applies the profile's line ending and title length limit
+func (p *Profile) formatTitle(title string) string {
+	if p == nil {
+		return normalizeLineEndings(title)
+	}
+	return p.format(title, p.MaxTitleLength)
+}
+
+// formatText applies the profile's line ending and text length limit
+func (p *Profile) formatText(text string) string {
+	if p == nil {
+		return normalizeLineEndings(text)
+	}
+	return p.format(text, p.MaxTextLength)
+}
+
+// format normalizes line endings, applies LineEnding and truncates s
+func (p *Profile) format(s string, maxLength int) string {
+	s = normalizeLineEndings(s)
+	if p.LineEnding == LineEndingSpace {
+		s = strings.ReplaceAll(s, "\n", " ")
+	}
+	return truncate(s, maxLength)
+}
+
+// normalizeLineEndings converts CRLF and CR to LF
+func normalizeLineEndings(s string) string {
+	if !strings.Contains(s, "\r") {
+		return s
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\r", "\n")
+}
+
+// truncate shortens s to at most maxLength characters, ending it with an
+// ellipsis. A maxLength of 0 means no limit.
+func truncate(s string, maxLength int) string {
+	if maxLength <= 0 || utf8.RuneCountInString(s) <= maxLength {
+		return s
+	}
+
+	runes := []rune(s)
+	keep := maxLength - utf8.RuneCountInString(truncationMarker)
+	if keep <= 0 {
+		return string(runes[:maxLength])
+	}
+	return string(runes[:keep]) + truncationMarker
+}
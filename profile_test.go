@@ -0,0 +1,145 @@
+package gntp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProfileFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile *Profile
+		in      string
+		title   string
+		text    string
+	}{
+		{"nil profile", nil, "a\r\nb\rc", "a\nb\nc", "a\nb\nc"},
+		{"LF", &Profile{}, "a\r\nb", "a\nb", "a\nb"},
+		{"space", &Profile{LineEnding: LineEndingSpace}, "a\r\nb\nc", "a b c", "a b c"},
+		{"within limits", &Profile{MaxTitleLength: 5, MaxTextLength: 5}, "hello", "hello", "hello"},
+		{"truncated", &Profile{MaxTitleLength: 4, MaxTextLength: 6}, "abcdefgh", "abc…", "abcde…"},
+		{"truncated runes", &Profile{MaxTitleLength: 3, MaxTextLength: 4}, "héllö wörld", "hé…", "hél…"},
+		{"limit of one", &Profile{MaxTitleLength: 1}, "日本語", "日", "日本語"},
+		{"truncated after line endings", &Profile{MaxTitleLength: 4, LineEnding: LineEndingSpace}, "ab\r\ncd\r\nef", "ab …", "ab cd ef"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.profile.formatTitle(tt.in); got != tt.title {
+				t.Errorf("formatTitle(%q) = %q, want %q", tt.in, got, tt.title)
+			}
+			if got := tt.profile.formatText(tt.in); got != tt.text {
+				t.Errorf("formatText(%q) = %q, want %q", tt.in, got, tt.text)
+			}
+		})
+	}
+}
+
+func TestLookupProfile(t *testing.T) {
+	custom := Profile{Name: "Custom", SoftwareNames: []string{"Custom Notifier", "CustomN"}}
+	RegisterProfile(custom)
+	t.Cleanup(func() {
+		profiles.Lock()
+		defer profiles.Unlock()
+		for _, name := range custom.SoftwareNames {
+			delete(profiles.bySoftware, strings.ToLower(name))
+		}
+	})
+
+	tests := []struct {
+		softwareName string
+		want         string // Profile name; "" if none
+	}{
+		{"Growl/Win", ProfileGrowlWindows.Name},
+		{"growl for windows", ProfileGrowlWindows.Name},
+		{"Growl", ProfileGrowlMac.Name},
+		{"GROWL FOR ANDROID", ProfileGrowlAndroid.Name},
+		{"Snarl", ProfileSnarl.Name},
+		{"custom notifier", "Custom"},
+		{"CustomN", "Custom"},
+		{"Unknown", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.softwareName, func(t *testing.T) {
+			p, ok := LookupProfile(tt.softwareName)
+			if ok != (tt.want != "") || p.Name != tt.want {
+				t.Errorf("LookupProfile(%q) = %q, %v; want %q", tt.softwareName, p.Name, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotifyHeaderGating(t *testing.T) {
+	options := NewNotifyOptions().
+		WithSticky(true).
+		WithPriority(2).
+		WithCoalescingID("job-1").
+		WithCallbackContext("ctx").
+		WithCallbackTarget("https://example.com/")
+
+	tests := []struct {
+		name    string
+		profile *Profile // nil uses no profile
+		want    []string // Headers expected
+		omitted []string // Headers expected to be absent
+	}{
+		{
+			name: "no profile",
+			want: []string{"Notification-Sticky", "Notification-Priority", "Notification-Coalescing-ID", "Notification-Callback-Context", "Notification-Callback-Target"},
+		},
+		{
+			name:    "all honored",
+			profile: &ProfileGrowlWindows,
+			want:    []string{"Notification-Sticky", "Notification-Priority", "Notification-Coalescing-ID", "Notification-Callback-Context", "Notification-Callback-Target"},
+		},
+		{
+			name:    "none honored",
+			profile: &Profile{Name: "Minimal", Callbacks: CallbackNone},
+			want:    []string{"Notification-Callback-Target"},
+			omitted: []string{"Notification-Sticky", "Notification-Priority", "Notification-Coalescing-ID", "Notification-Callback-Context", "Notification-Callback-Context-Type"},
+		},
+		{
+			name:    "sticky only",
+			profile: &Profile{Name: "Sticky", Sticky: true},
+			want:    []string{"Notification-Sticky", "Notification-Callback-Context"},
+			omitted: []string{"Notification-Priority", "Notification-Coalescing-ID"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t)
+			client := server.client("Gating")
+			defer client.Close()
+			if err := client.WithCallback(func(CallbackInfo) {}); err != nil {
+				t.Fatal(err)
+			}
+			if tt.profile != nil {
+				client.WithProfile(*tt.profile)
+			}
+			if err := client.Register([]*NotificationType{NewNotificationType("job")}); err != nil {
+				t.Fatal(err)
+			}
+			if err := client.NotifyWithOptions("job", "Title", "Text", options); err != nil {
+				t.Fatal(err)
+			}
+
+			notify := server.received("NOTIFY")
+			if len(notify) != 1 {
+				t.Fatalf("received %d notifications, want 1", len(notify))
+			}
+			for _, header := range tt.want {
+				if _, ok := notify[0].Headers[header]; !ok {
+					t.Errorf("%s not sent", header)
+				}
+			}
+			for _, header := range tt.omitted {
+				if value, ok := notify[0].Headers[header]; ok {
+					t.Errorf("%s sent as %q", header, value)
+				}
+			}
+		})
+	}
+}
@@ -2,100 +2,156 @@ package gntp
 
 import (
 	"bufio"
+	"context"
+	"crypto/md5"
 	"fmt"
+	"io"
+	"log/slog"
 	"net"
+	"strconv"
 	"strings"
-	"crypto/md5"
 	"time"
-	"io"
 )
 
 // Register registers the application and notification types with Growl
 func (c *Client) Register(notifications []*NotificationType) error {
-	var packet strings.Builder
-	resources := make([]*Resource, 0)
-	seenIDs := make(map[string]bool)
-	
-	// Build REGISTER packet
-	packet.WriteString(fmt.Sprintf("GNTP/%s REGISTER NONE%s", GNTPVersion, CRLF))
-	packet.WriteString(fmt.Sprintf("Application-Name: %s%s", c.ApplicationName, CRLF))
-	
+	return c.RegisterContext(context.Background(), notifications)
+}
+
+// RegisterContext registers the application and notification types with Growl.
+// The context bounds the connection and the wait for the response. Invalid
+// notification types fail with a ValidationError before anything is sent.
+func (c *Client) RegisterContext(ctx context.Context, notifications []*NotificationType) error {
+	app := c.defaultApplication()
+	app.Notifications = notifications
+	return c.registerApplication(ctx, app)
+}
+
+// registerApplication registers app unless the registration store shows
+// the destination already has the same registration
+func (c *Client) registerApplication(ctx context.Context, app *Application) error {
+	if err := app.Validate(); err != nil {
+		return err
+	}
+	state := newApplicationState(app, app.Notifications)
+	if err := c.localizer().prepare(state); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	store := c.registrationStore
+	c.mu.Unlock()
+
+	var reg *Registration
+	if store != nil {
+		reg = c.registration(app)
+		prev, err := store.Load(c.address(), app.Name)
+		if err != nil {
+			c.log().WarnContext(ctx, "gntp registration store failed", slog.Any("error", err))
+		}
+		if reg.sameAs(prev) {
+			c.log().DebugContext(ctx, "gntp registration unchanged",
+				slog.String("destination", c.address()),
+				slog.String("application", app.Name),
+			)
+			c.setRegistered(state)
+			return nil
+		}
+	}
+
+	build := func() (*Request, error) {
+		return c.buildRegister(app)
+	}
+	if _, err := c.sendRequest(ctx, build); err != nil {
+		return err
+	}
+
+	if store != nil {
+		reg.RegisteredAt = time.Now()
+		if err := store.Save(c.address(), app.Name, reg); err != nil {
+			c.log().WarnContext(ctx, "gntp registration store failed", slog.Any("error", err))
+		}
+	}
+
+	c.setRegistered(state)
+	return nil
+}
+
+// reregister registers app again with its last registered notification
+// types after the server reported them unknown. It reports whether it did.
+func (c *Client) reregister(ctx context.Context, app *Application) bool {
+	c.mu.Lock()
+	store := c.registrationStore
+	c.mu.Unlock()
+
+	state := c.registered(app.Name)
+	if store == nil || state == nil {
+		return false
+	}
+
+	if err := store.Delete(c.address(), app.Name); err != nil {
+		c.log().WarnContext(ctx, "gntp registration store failed", slog.Any("error", err))
+	}
+
+	registered := state.app
+	registered.Notifications = state.notifications
+	return c.registerApplication(ctx, &registered) == nil
+}
+
+// buildRegister builds a REGISTER request
+func (c *Client) buildRegister(app *Application) (*Request, error) {
+	req := &Request{Type: RequestRegister}
+
+	req.Headers.Add("Application-Name", app.Name)
+
 	// Application icon
-	if c.ApplicationIcon != nil {
-		iconRef := c.ApplicationIcon.getReference(c.IconMode)
-		packet.WriteString(fmt.Sprintf("Application-Icon: %s%s", iconRef, CRLF))
-		
-		if c.IconMode == IconModeBinary && !seenIDs[c.ApplicationIcon.Identifier] {
-			resources = append(resources, c.ApplicationIcon)
-			seenIDs[c.ApplicationIcon.Identifier] = true
+	if app.Icon != nil {
+		iconRef, err := c.iconReference(req, app.Icon)
+		if err != nil {
+			return nil, err
 		}
+		req.Headers.Add("Application-Icon", iconRef)
 	}
-	
+
+	app.addOrigin(req)
+
+	profile := c.profile()
+
 	// Callback URL if handler is set
-	if c.callbackURL != "" {
-		packet.WriteString(fmt.Sprintf("Notification-Callback-Target: %s%s", c.callbackURL, CRLF))
+	if c.callbackURL != "" && profile.callbacks() {
+		req.Headers.Add("Notification-Callback-Target", c.callbackURL)
 	}
-	
-	packet.WriteString(fmt.Sprintf("Notifications-Count: %d%s", len(notifications), CRLF))
-	packet.WriteString(CRLF)
-	
-	// Each notification type
-	for _, notif := range notifications {
-		packet.WriteString(fmt.Sprintf("Notification-Name: %s%s", notif.Name, CRLF))
-		
-		if notif.DisplayName != "" {
-			packet.WriteString(fmt.Sprintf("Notification-Display-Name: %s%s", notif.DisplayName, CRLF))
+
+	req.Headers.Add("Notifications-Count", strconv.Itoa(len(app.Notifications)))
+
+	// Each notification type, with display names in the destination locale
+	l := c.localizer()
+	for _, notif := range app.Notifications {
+		var section Headers
+		section.Add("Notification-Name", notif.Name)
+
+		if displayName := l.displayName(notif); displayName != "" {
+			section.Add("Notification-Display-Name", displayName)
 		}
-		
+
 		enabled := "False"
 		if notif.Enabled {
 			enabled = "True"
 		}
-		packet.WriteString(fmt.Sprintf("Notification-Enabled: %s%s", enabled, CRLF))
-		
+		section.Add("Notification-Enabled", enabled)
+
 		if notif.Icon != nil {
-			iconRef := notif.Icon.getReference(c.IconMode)
-			packet.WriteString(fmt.Sprintf("Notification-Icon: %s%s", iconRef, CRLF))
-			
-			if c.IconMode == IconModeBinary && !seenIDs[notif.Icon.Identifier] {
-				resources = append(resources, notif.Icon)
-				seenIDs[notif.Icon.Identifier] = true
+			iconRef, err := c.iconReference(req, notif.Icon)
+			if err != nil {
+				return nil, err
 			}
+			section.Add("Notification-Icon", iconRef)
 		}
-		
-		packet.WriteString(CRLF)
-	}
-	
-	// Binary resources
-	if c.IconMode == IconModeBinary {
-		for _, res := range resources {
-			packet.WriteString(fmt.Sprintf("Identifier: %s%s", res.Identifier, CRLF))
-			packet.WriteString(fmt.Sprintf("Length: %d%s", len(res.Data), CRLF))
-			packet.WriteString(CRLF)
-		}
-	}
-	
-	if c.Debug {
-		fmt.Printf("\n=== REGISTER PACKET (Mode: %d) ===\n", c.IconMode)
-		fmt.Println(packet.String())
-		fmt.Printf("Resources: %d\n", len(resources))
-		fmt.Println("======================================\n")
-	}
-	
-	// Send packet
-	var err error
-	if c.IconMode == IconModeBinary {
-		_, err = c.sendPacketWithResources(packet.String(), resources)
-	} else {
-		_, err = c.sendPacket(packet.String())
-	}
-	
-	if err != nil {
-		return err
+
+		req.Sections = append(req.Sections, section)
 	}
-	
-	c.registered = true
-	return nil
+
+	return req, nil
 }
 
 // Notify sends a notification
@@ -105,77 +161,179 @@ func (c *Client) Notify(notificationName, title, text string) error {
 
 // NotifyWithOptions sends a notification with options
 func (c *Client) NotifyWithOptions(notificationName, title, text string, options *NotifyOptions) error {
-	if !c.registered {
-		return fmt.Errorf("must call Register() before Notify()")
+	return c.NotifyContext(context.Background(), notificationName, title, text, options)
+}
+
+// NotifyContext sends a notification with options. The context bounds rate
+// limit delays, the connection and the wait for the response. Unregistered
+// names and invalid options fail with a ValidationError before anything is
+// sent. Text in Markdown or HTML is converted to plain text first.
+func (c *Client) NotifyContext(ctx context.Context, notificationName, title, text string, options *NotifyOptions) error {
+	if options == nil {
+		options = NewNotifyOptions()
 	}
 	
-	var packet strings.Builder
-	resources := make([]*Resource, 0)
-	
-	// Generate notification ID for callbacks
-	notificationID := fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf("%s:%s:%d", c.ApplicationName, notificationName, time.Now().UnixNano()))))
-	
-	packet.WriteString(fmt.Sprintf("GNTP/%s NOTIFY NONE%s", GNTPVersion, CRLF))
-	packet.WriteString(fmt.Sprintf("Application-Name: %s%s", c.ApplicationName, CRLF))
-	packet.WriteString(fmt.Sprintf("Notification-Name: %s%s", notificationName, CRLF))
-	packet.WriteString(fmt.Sprintf("Notification-ID: %s%s", notificationID, CRLF))
-	packet.WriteString(fmt.Sprintf("Notification-Title: %s%s", title, CRLF))
-	packet.WriteString(fmt.Sprintf("Notification-Text: %s%s", text, CRLF))
-	
-	if options.Sticky {
-		packet.WriteString(fmt.Sprintf("Notification-Sticky: True%s", CRLF))
+	state := c.registered(c.ApplicationName)
+	if state == nil {
+		return fmt.Errorf("must call Register() before Notify()")
 	}
-	
-	if options.Priority != 0 {
-		packet.WriteString(fmt.Sprintf("Notification-Priority: %d%s", options.Priority, CRLF))
+	text, options = renderText(text, options)
+	if err := c.validateNotify(state, notificationName, title, text, options); err != nil {
+		return err
 	}
 	
-	if options.Icon != nil {
-		iconRef := options.Icon.getReference(c.IconMode)
-		packet.WriteString(fmt.Sprintf("Notification-Icon: %s%s", iconRef, CRLF))
-		
-		if c.IconMode == IconModeBinary {
-			resources = append(resources, options.Icon)
+	return c.notifyRegistered(ctx, c.defaultApplication(), notificationName, title, text, options)
+}
+
+// notifyApplication checks that app is registered and sends a notification
+func (c *Client) notifyApplication(ctx context.Context, app *Application, notificationName, title, text string, options *NotifyOptions) error {
+	if options == nil {
+		options = NewNotifyOptions()
+	}
+	state := c.registered(app.Name)
+	if state == nil {
+		return errNotRegistered(app)
+	}
+	text, options = renderText(text, options)
+	if err := c.validateNotify(state, notificationName, title, text, options); err != nil {
+		return err
+	}
+	return c.notifyRegistered(ctx, app, notificationName, title, text, options)
+}
+
+// notifyRegistered suppresses duplicates, applies the rate limits and sends
+// the notification
+func (c *Client) notifyRegistered(ctx context.Context, app *Application, notificationName, title, text string, options *NotifyOptions) error {
+	c.mu.Lock()
+	dedup := c.dedup
+	c.mu.Unlock()
+	
+	var pending *dedupEntry
+	if dedup != nil {
+		entry, duplicate := dedup.suppress(app, notificationName, title, text, options)
+		if duplicate {
+			return nil
 		}
+		pending = entry
 	}
 	
-	// Callback settings
-	if c.callbackURL != "" {
-		packet.WriteString(fmt.Sprintf("Notification-Callback-Context: %s%s", options.CallbackContext, CRLF))
-		packet.WriteString(fmt.Sprintf("Notification-Callback-Context-Type: string%s", CRLF))
-		
-		if options.CallbackTarget != "" {
-			packet.WriteString(fmt.Sprintf("Notification-Callback-Target: %s%s", options.CallbackTarget, CRLF))
+	sent, err := c.notifyLimited(ctx, app, notificationName, title, text, options)
+	if pending != nil {
+		if sent {
+			dedup.sent(c, pending)
+		} else {
+			dedup.failed(pending)
 		}
 	}
-	
-	packet.WriteString(CRLF)
-	
-	// Binary resources
-	if c.IconMode == IconModeBinary {
-		for _, res := range resources {
-			packet.WriteString(fmt.Sprintf("Identifier: %s%s", res.Identifier, CRLF))
-			packet.WriteString(fmt.Sprintf("Length: %d%s", len(res.Data), CRLF))
-			packet.WriteString(CRLF)
+	return err
+}
+
+// notifyLimited applies the rate limits and sends the notification,
+// reporting whether it was delivered
+func (c *Client) notifyLimited(ctx context.Context, app *Application, notificationName, title, text string, options *NotifyOptions) (bool, error) {
+	c.mu.Lock()
+	limiter := c.limiter
+	c.mu.Unlock()
+	
+	if limiter != nil {
+		send, err := limiter.allow(ctx, c, app, notificationName, title, options)
+		if !send {
+			return false, err
 		}
 	}
 	
-	if c.Debug {
-		fmt.Printf("\n=== NOTIFY PACKET (Mode: %d) ===\n", c.IconMode)
-		fmt.Println(packet.String())
-		fmt.Println("====================================\n")
+	if err := c.notify(ctx, app, notificationName, title, text, options); err != nil {
+		return false, err
 	}
-	
-	// Send packet
-	if c.IconMode == IconModeBinary {
-		_, err := c.sendPacketWithResources(packet.String(), resources)
-		return err
+	return true, nil
+}
+
+// notify builds and sends a NOTIFY request
+func (c *Client) notify(ctx context.Context, app *Application, notificationName, title, text string, options *NotifyOptions) error {
+	build := func() (*Request, error) {
+		return c.buildNotify(app, notificationName, title, text, options)
+	}
+	_, err := c.sendRequest(ctx, build)
+	if err != nil && unknownRegistration(err) && c.reregister(ctx, app) {
+		_, err = c.sendRequest(ctx, build)
 	}
-	
-	_, err := c.sendPacket(packet.String())
 	return err
 }
 
+// buildNotify builds a NOTIFY request
+func (c *Client) buildNotify(app *Application, notificationName, title, text string, options *NotifyOptions) (*Request, error) {
+	req := &Request{Type: RequestNotify}
+
+	// Generate notification ID for callbacks
+	notificationID := fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf("%s:%s:%d", app.Name, notificationName, time.Now().UnixNano()))))
+
+	req.Headers.Add("Application-Name", app.Name)
+	req.Headers.Add("Notification-Name", notificationName)
+	req.Headers.Add("Notification-ID", notificationID)
+	app.addOrigin(req)
+	profile := c.profile()
+
+	req.Headers.Add("Notification-Title", profile.formatTitle(title))
+	req.Headers.Add("Notification-Text", profile.formatText(text))
+
+	if options.Sticky && (profile == nil || profile.Sticky) {
+		req.Headers.Add("Notification-Sticky", "True")
+	}
+
+	if options.Priority != 0 && (profile == nil || profile.Priority) {
+		req.Headers.Add("Notification-Priority", strconv.Itoa(options.Priority))
+	}
+
+	if options.CoalescingID != "" && (profile == nil || profile.Coalescing) {
+		req.Headers.Add("Notification-Coalescing-ID", options.CoalescingID)
+	}
+
+	if options.Icon != nil {
+		iconRef, err := c.iconReference(req, options.Icon)
+		if err != nil {
+			return nil, err
+		}
+		req.Headers.Add("Notification-Icon", iconRef)
+	}
+
+	// Callback settings
+	if c.callbackURL != "" && profile.callbacks() {
+		req.Headers.Add("Notification-Callback-Context", options.CallbackContext)
+		req.Headers.Add("Notification-Callback-Context-Type", "string")
+	}
+	if options.CallbackTarget != "" {
+		req.Headers.Add("Notification-Callback-Target", options.CallbackTarget)
+	}
+
+	return req, nil
+}
+
+// sendRequest builds and sends a request. If IconModeAuto referenced icons
+// in a way the server rejected, the request is rebuilt with data URLs and
+// sent again, and later requests to the server use data URLs.
+func (c *Client) sendRequest(ctx context.Context, build func() (*Request, error)) (*Response, error) {
+	req, err := build()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.sendCached(ctx, req)
+	if err == nil || !req.autoIcons || !iconFallbackCode(ErrorCode(err)) {
+		return resp, err
+	}
+
+	c.log().WarnContext(ctx, "gntp falling back to data URL icons",
+		slog.String("destination", c.address()),
+		slog.Any("error", err),
+	)
+	c.fallBackToDataURL()
+
+	if req, err = build(); err != nil {
+		return nil, err
+	}
+	return c.sendCached(ctx, req)
+}
+
 // SendMessage sends a notification using Message struct (compatibility method)
 func (c *Client) SendMessage(msg *Message) error {
 	// Load icon if specified
@@ -189,7 +347,7 @@ func (c *Client) SendMessage(msg *Message) error {
 	}
 	
 	// Create notification type if not registered
-	if !c.registered {
+	if c.registered(c.ApplicationName) == nil {
 		displayName := msg.DisplayName
 		if displayName == "" {
 			displayName = msg.Event
@@ -220,129 +378,186 @@ func (c *Client) SendMessage(msg *Message) error {
 	return c.NotifyWithOptions(msg.Event, msg.Title, msg.Text, options)
 }
 
-// sendPacket sends a text-only packet
-func (c *Client) sendPacket(packet string) (string, error) {
-	address := fmt.Sprintf("%s:%d", c.Host, c.Port)
-	
-	if c.Debug {
-		fmt.Printf("Connecting to %s...\n", address)
+// transport is the innermost Sender: it writes the request to the server
+func (c *Client) transport(ctx context.Context, req *Request) (*Response, error) {
+	logger := c.log()
+	start := time.Now()
+
+	attrs := []any{
+		slog.String("destination", c.address()),
+		slog.String("request", string(req.Type)),
 	}
-	
-	conn, err := net.DialTimeout("tcp", address, c.Timeout)
-	if err != nil {
-		return "", fmt.Errorf("failed to connect to %s: %w", address, err)
+	if id := req.Headers.Get("Notification-ID"); id != "" {
+		attrs = append(attrs, slog.String("notification_id", id))
 	}
-	defer conn.Close()
-	
-	// Set deadlines
-	conn.SetDeadline(time.Now().Add(c.Timeout))
-	
-	// Send packet
-	if _, err := conn.Write([]byte(packet)); err != nil {
-		return "", fmt.Errorf("failed to send packet: %w", err)
+
+	if logger.Enabled(ctx, slog.LevelDebug) {
+		logger.DebugContext(ctx, "gntp packet", append(attrs,
+			slog.Int("icon_mode", int(c.IconMode)),
+			slog.Int("resources", len(req.Resources)),
+			slog.String("packet", redactPacket(req.encode())),
+		)...)
 	}
-	
-	if c.Debug {
-		fmt.Println("Packet sent, waiting for response...")
+
+	metrics := c.instruments()
+	if metrics != nil {
+		metrics.RequestStarted(req.Type)
 	}
-	
-	// Read response
-	reader := bufio.NewReader(conn)
-	response := strings.Builder{}
-	
-	for {
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			// Connection closed is OK for some Growl versions
-			if strings.Contains(err.Error(), "connection") {
-				break
-			}
-			return "", fmt.Errorf("failed to read response: %w", err)
+
+	raw, bytes, err := c.sendPacket(ctx, req)
+
+	var resp *Response
+	if err == nil {
+		resp = parseResponse(raw)
+		c.recordServerInfo(resp)
+		if resp.Status == "ERROR" {
+			err = newServerError(resp)
 		}
-		response.WriteString(line)
-		
-		// Check for end of response
-		if strings.TrimSpace(line) == "" {
-			break
+	}
+
+	duration := time.Since(start)
+	attrs = append(attrs,
+		slog.Duration("duration", duration),
+		slog.Int("bytes", bytes),
+	)
+	if err != nil {
+		code := ErrorCode(err)
+		if code != 0 {
+			attrs = append(attrs, slog.Int("error_code", code))
 		}
+		if metrics != nil {
+			metrics.RequestFailed(req.Type, code, duration)
+		}
+		logger.WarnContext(ctx, "gntp request failed", append(attrs, slog.Any("error", err))...)
+		return nil, err
 	}
-	
-	responseStr := response.String()
-	
-	if c.Debug {
-		fmt.Printf("Response:\n%s\n", responseStr)
+
+	if metrics != nil {
+		metrics.RequestSucceeded(req.Type, duration, bytes)
 	}
-	
-	// Check for errors
-	if strings.Contains(responseStr, "-ERROR") {
-		return "", fmt.Errorf("server error: %s", responseStr)
+
+	logger.DebugContext(ctx, "gntp request sent", append(attrs, slog.String("response", redactPacket(raw)))...)
+	return resp, nil
+}
+
+// resourceHeader returns the identifier and length block of a binary resource
+func resourceHeader(res *Resource) string {
+	return fmt.Sprintf("Identifier: %s%sLength: %d%s%s", res.Identifier, CRLF, res.Size(), CRLF, CRLF)
+}
+
+// address returns the host:port of the Growl server
+func (c *Client) address() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+// dial connects to the Growl server and sets the connection deadline
+func (c *Client) dial(ctx context.Context) (net.Conn, error) {
+	address := c.address()
+
+	dialer := net.Dialer{Timeout: c.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
 	}
-	
-	return responseStr, nil
+
+	deadline := time.Now().Add(c.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	return conn, nil
 }
 
-// sendPacketWithResources sends a packet with binary resources
-func (c *Client) sendPacketWithResources(packet string, resources []*Resource) (string, error) {
-	address := fmt.Sprintf("%s:%d", c.Host, c.Port)
-	
-	conn, err := net.DialTimeout("tcp", address, c.Timeout)
+// sendPacket writes the request and its binary resources to the server
+// through a buffer, streaming resource content, and returns the raw
+// response and the number of bytes sent
+func (c *Client) sendPacket(ctx context.Context, req *Request) (string, int, error) {
+	tracer := c.tracing()
+
+	var conn net.Conn
+	err := traceSpan(ctx, tracer, "gntp.dial", func(ctx context.Context) error {
+		var err error
+		conn, err = c.dial(ctx)
+		return err
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to connect to %s: %w", address, err)
+		return "", 0, err
 	}
 	defer conn.Close()
-	
-	conn.SetDeadline(time.Now().Add(c.Timeout))
-	
-	// Send text packet
-	if _, err := conn.Write([]byte(packet)); err != nil {
-		return "", fmt.Errorf("failed to send packet: %w", err)
-	}
-	
-	// Send binary resources
-	for _, res := range resources {
-		if _, err := conn.Write(res.Data); err != nil {
-			return "", fmt.Errorf("failed to send resource data: %w", err)
+
+	// Cancelling ctx interrupts a write or read in progress
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	counter := &countingWriter{w: conn}
+	err = traceSpan(ctx, tracer, "gntp.write", func(ctx context.Context) error {
+		w := bufio.NewWriter(counter)
+
+		if err := req.writeTo(w); err != nil {
+			return fmt.Errorf("failed to send packet: %w", err)
 		}
-		if _, err := conn.Write([]byte(CRLF)); err != nil {
-			return "", fmt.Errorf("failed to send resource CRLF: %w", err)
+
+		// Send binary resources, each preceded by its identifier and length
+		for _, res := range req.Resources {
+			if err := writeResource(w, res); err != nil {
+				return err
+			}
 		}
+
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("failed to send packet: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", int(counter.n), contextError(ctx, err)
 	}
-	
-	// Message termination
-	if _, err := conn.Write([]byte(CRLF)); err != nil {
-		return "", fmt.Errorf("failed to send termination: %w", err)
+
+	var response string
+	err = traceSpan(ctx, tracer, "gntp.read", func(ctx context.Context) error {
+		var err error
+		response, err = readResponse(conn)
+		return err
+	})
+	return response, int(counter.n), contextError(ctx, err)
+}
+
+// contextError returns err wrapped with ctx.Err() if ctx is done, since
+// cancellation shows up on the connection as a deadline error
+func contextError(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("%w: %w", ctx.Err(), err)
 	}
-	
-	// Read response
+	return err
+}
+
+// readResponse reads a response up to the terminating blank line
+func readResponse(conn net.Conn) (string, error) {
 	reader := bufio.NewReader(conn)
 	response := strings.Builder{}
-	
+
 	for {
 		line, err := reader.ReadString('\n')
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
+			// Connection closed is OK for some Growl versions
 			if strings.Contains(err.Error(), "connection") {
 				break
 			}
 			return "", fmt.Errorf("failed to read response: %w", err)
 		}
 		response.WriteString(line)
-		
+
+		// Check for end of response
 		if strings.TrimSpace(line) == "" {
 			break
 		}
 	}
-	
-	responseStr := response.String()
-	
-	if strings.Contains(responseStr, "-ERROR") {
-		return "", fmt.Errorf("server error: %s", responseStr)
-	}
-	
-	return responseStr, nil
-}
\ No newline at end of file
+
+	return response.String(), nil
+}
@@ -0,0 +1,253 @@
+package gntp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// RateLimitAction specifies what happens to notifications over the rate limit
+type RateLimitAction int
+
+const (
+	// RateLimitDelay waits until the notification may be sent or the
+	// context passed to NotifyContext is done
+	RateLimitDelay RateLimitAction = iota
+
+	// RateLimitDrop discards the notification and returns ErrRateLimited
+	RateLimitDrop
+
+	// RateLimitCollapse discards the notification and sends a single
+	// "N more events suppressed" summary once the limit allows it again
+	RateLimitCollapse
+)
+
+// ErrRateLimited is returned when a notification is dropped by RateLimitDrop
+var ErrRateLimited = errors.New("gntp: notification rate limited")
+
+// RateLimit configures a token bucket. A Rate of zero or less means no limit.
+type RateLimit struct {
+	Rate   float64         // Notifications per second
+	Burst  int             // Notifications that may be sent back-to-back
+	Action RateLimitAction // What to do with excess notifications
+}
+
+// tokenBucket tracks the tokens and suppressed notifications of one limit
+type tokenBucket struct {
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+
+	// Collapse state
+	suppressed  int
+	lastApp     *Application
+	lastName    string
+	lastTitle   string
+	lastOptions NotifyOptions
+	timer       *time.Timer
+}
+
+// rateLimiter holds the global and per notification type buckets
+type rateLimiter struct {
+	mu      sync.Mutex
+	global  *tokenBucket
+	perType map[string]*tokenBucket
+	stopped bool
+}
+
+// WithRateLimit limits the rate of all notifications sent by the client. A
+// limit with no Rate removes it.
+func (c *Client) WithRateLimit(limit RateLimit) *Client {
+	l := c.rateLimiter()
+	l.mu.Lock()
+	l.global = newTokenBucket(limit)
+	l.mu.Unlock()
+	return c
+}
+
+// WithTypeRateLimit limits the rate of notifications with the given name.
+// It applies in addition to the global limit set with WithRateLimit. A limit
+// with no Rate removes it.
+func (c *Client) WithTypeRateLimit(notificationName string, limit RateLimit) *Client {
+	l := c.rateLimiter()
+	l.mu.Lock()
+	if b := newTokenBucket(limit); b != nil {
+		l.perType[notificationName] = b
+	} else {
+		delete(l.perType, notificationName)
+	}
+	l.mu.Unlock()
+	return c
+}
+
+// rateLimiter returns the client's limiter, creating it if needed
+func (c *Client) rateLimiter() *rateLimiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.limiter == nil {
+		c.limiter = &rateLimiter{perType: make(map[string]*tokenBucket)}
+	}
+	return c.limiter
+}
+
+// newTokenBucket creates a full bucket, or returns nil if limit has no rate
+func newTokenBucket(limit RateLimit) *tokenBucket {
+	if limit.Rate <= 0 {
+		return nil
+	}
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return &tokenBucket{
+		limit:  limit,
+		tokens: float64(limit.Burst),
+		last:   time.Now(),
+	}
+}
+
+// refill adds the tokens accumulated since the last call
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.limit.Rate
+	if b.tokens > float64(b.limit.Burst) {
+		b.tokens = float64(b.limit.Burst)
+	}
+	b.last = now
+}
+
+// wait returns how long until a token is available
+func (b *tokenBucket) wait() time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.limit.Rate * float64(time.Second))
+}
+
+// allow applies the type and global limits to a notification. It returns
+// false if the notification must not be sent now. Tokens are only taken
+// once every bucket lets the notification through, so one that is dropped
+// or collapsed does not use up the budget of the others.
+func (l *rateLimiter) allow(ctx context.Context, c *Client, app *Application, notificationName, title string, options *NotifyOptions) (bool, error) {
+	l.mu.Lock()
+	buckets := make([]*tokenBucket, 0, 2)
+	if b, ok := l.perType[notificationName]; ok {
+		buckets = append(buckets, b)
+	}
+	if l.global != nil {
+		buckets = append(buckets, l.global)
+	}
+
+	var delay time.Duration
+	now := time.Now()
+	for _, b := range buckets {
+		b.refill(now)
+		wait := b.wait()
+		if wait == 0 {
+			continue
+		}
+
+		switch b.limit.Action {
+		case RateLimitDrop:
+			l.mu.Unlock()
+			return false, ErrRateLimited
+
+		case RateLimitCollapse:
+			b.suppressed++
+			b.lastApp = app
+			b.lastName = notificationName
+			b.lastTitle = title
+			b.lastOptions = *options
+			if b.timer == nil && !l.stopped {
+				b.timer = time.AfterFunc(wait, func() {
+					l.flushSuppressed(c, b)
+				})
+			}
+			l.mu.Unlock()
+			return false, nil
+
+		default: // RateLimitDelay
+			delay = max(delay, wait)
+		}
+	}
+
+	// Take the tokens, in advance when delayed so later callers queue behind us
+	for _, b := range buckets {
+		b.tokens--
+	}
+	l.mu.Unlock()
+
+	if delay > 0 {
+		c.log().DebugContext(ctx, "gntp rate limited",
+			slog.String("notification", notificationName),
+			slog.Duration("delay", delay),
+		)
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			// Not sent: give the tokens back
+			l.mu.Lock()
+			for _, b := range buckets {
+				b.tokens = min(b.tokens+1, float64(b.limit.Burst))
+			}
+			l.mu.Unlock()
+			return false, ctx.Err()
+		}
+	}
+	return true, nil
+}
+
+// flushSuppressed sends the summary for a collapsed bucket
+func (l *rateLimiter) flushSuppressed(c *Client, b *tokenBucket) {
+	l.mu.Lock()
+	b.timer = nil
+	if l.stopped || b.suppressed == 0 {
+		l.mu.Unlock()
+		return
+	}
+	count := b.suppressed
+	app := b.lastApp
+	name := b.lastName
+	title := b.lastTitle
+	options := b.lastOptions
+	b.suppressed = 0
+	b.refill(time.Now())
+	b.tokens--
+	l.mu.Unlock()
+
+	summary := fmt.Sprintf("%d more events suppressed", count)
+	text := ""
+	if title != "" {
+		text = fmt.Sprintf("Last: %s", title)
+	}
+
+	if err := c.notify(context.Background(), app, name, summary, text, &options); err != nil {
+		c.reportAsyncError(err)
+	}
+}
+
+// stop cancels pending summaries
+func (l *rateLimiter) stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.stopped = true
+	buckets := make([]*tokenBucket, 0, len(l.perType)+1)
+	for _, b := range l.perType {
+		buckets = append(buckets, b)
+	}
+	if l.global != nil {
+		buckets = append(buckets, l.global)
+	}
+	for _, b := range buckets {
+		if b.timer != nil {
+			b.timer.Stop()
+			b.timer = nil
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package gntp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimitDrop(t *testing.T) {
+	server := newFakeServer(t)
+	client := server.client("Limited").
+		WithRateLimit(RateLimit{Rate: 0.01, Burst: 2, Action: RateLimitDrop})
+	if err := client.Register([]*NotificationType{NewNotificationType("n")}); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var sent, dropped int
+	for i := 0; i < 4; i++ {
+		switch err := client.Notify("n", "title", ""); {
+		case err == nil:
+			sent++
+		case errors.Is(err, ErrRateLimited):
+			dropped++
+		default:
+			t.Fatal(err)
+		}
+	}
+	if sent != 2 || dropped != 2 {
+		t.Errorf("sent %d, dropped %d; want 2 and 2", sent, dropped)
+	}
+}
+
+func TestRateLimitRejectedKeepsTypeTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		action RateLimitAction
+	}{
+		{"drop", RateLimitDrop},
+		{"collapse", RateLimitCollapse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient("Limited").
+				WithTypeRateLimit("n", RateLimit{Rate: 0.01, Burst: 3, Action: RateLimitDrop}).
+				WithRateLimit(RateLimit{Rate: 0.01, Burst: 1, Action: tt.action})
+			defer client.Close()
+			app := client.defaultApplication()
+			options := NewNotifyOptions()
+
+			// The first passes both limits, the rest are rejected by the global one
+			for i := 0; i < 3; i++ {
+				client.limiter.allow(context.Background(), client, app, "n", "title", options)
+			}
+
+			client.limiter.mu.Lock()
+			tokens := client.limiter.perType["n"].tokens
+			client.limiter.mu.Unlock()
+			if tokens < 1.9 {
+				t.Errorf("type bucket has %.2f tokens, want 2", tokens)
+			}
+		})
+	}
+}
+
+func TestRateLimitDelayCancelled(t *testing.T) {
+	client := NewClient("Limited").
+		WithRateLimit(RateLimit{Rate: 0.01, Burst: 1, Action: RateLimitDelay})
+	defer client.Close()
+	app := client.defaultApplication()
+	options := NewNotifyOptions()
+
+	if ok, _ := client.limiter.allow(context.Background(), client, app, "n", "", options); !ok {
+		t.Fatal("first notification was not allowed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.limiter.allow(ctx, client, app, "n", "", options); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("allow = %v, want DeadlineExceeded", err)
+	}
+
+	client.limiter.mu.Lock()
+	tokens := client.limiter.global.tokens
+	client.limiter.mu.Unlock()
+	if tokens < -0.1 {
+		t.Errorf("cancelled notification kept its token: %.2f left", tokens)
+	}
+}
+
+func TestRateLimitZeroRateRemovesLimit(t *testing.T) {
+	client := NewClient("Limited").
+		WithRateLimit(RateLimit{Rate: 1, Burst: 1, Action: RateLimitDrop}).
+		WithTypeRateLimit("n", RateLimit{Rate: 1, Burst: 1, Action: RateLimitDrop}).
+		WithRateLimit(RateLimit{}).
+		WithTypeRateLimit("n", RateLimit{Action: RateLimitDelay})
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i := 0; i < 5; i++ {
+		ok, err := client.limiter.allow(ctx, client, client.defaultApplication(), "n", "", NewNotifyOptions())
+		if !ok || err != nil {
+			t.Fatalf("notification %d: allow = %v, %v", i, ok, err)
+		}
+	}
+}
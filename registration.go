@@ -0,0 +1,193 @@
+package gntp
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Registration is what a REGISTER request told a server, as remembered by a
+// RegistrationStore
+type Registration struct {
+	Application    string
+	Icon           string // Resource identifier of the application icon
+	IconMode       IconMode
+	CallbackTarget string
+	Origin         Origin
+	Types          []RegisteredType // Sorted by name
+	RegisteredAt   time.Time
+}
+
+// RegisteredType is a registered notification type
+type RegisteredType struct {
+	Name        string
+	DisplayName string
+	Enabled     bool
+	Icon        string // Resource identifier of the icon
+}
+
+// RegistrationStore remembers registrations across process starts so
+// Register can skip requests that would change nothing
+type RegistrationStore interface {
+	// Load returns the registration of application at destination, or nil
+	// if there is none
+	Load(destination, application string) (*Registration, error)
+
+	// Save records the registration of application at destination
+	Save(destination, application string, reg *Registration) error
+
+	// Delete forgets the registration of application at destination
+	Delete(destination, application string) error
+}
+
+// FileRegistrationStore keeps one JSON file per destination and application
+type FileRegistrationStore struct {
+	Dir string
+}
+
+// NewFileRegistrationStore creates a store in dir, or in go-gntp under the
+// user cache directory if dir is empty
+func NewFileRegistrationStore(dir string) (*FileRegistrationStore, error) {
+	if dir == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find cache directory: %w", err)
+		}
+		dir = filepath.Join(cacheDir, "go-gntp")
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create registration store: %w", err)
+	}
+	return &FileRegistrationStore{Dir: dir}, nil
+}
+
+// path returns the file of application at destination
+func (s *FileRegistrationStore) path(destination, application string) string {
+	key := md5.Sum([]byte(destination + "\n" + application))
+	return filepath.Join(s.Dir, fmt.Sprintf("%x.json", key))
+}
+
+// Load reads the registration file, returning nil if it does not exist
+func (s *FileRegistrationStore) Load(destination, application string) (*Registration, error) {
+	data, err := os.ReadFile(s.path(destination, application))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registration: %w", err)
+	}
+
+	var reg Registration
+	if err := json.Unmarshal(data, &reg); err != nil {
+		return nil, fmt.Errorf("failed to parse registration: %w", err)
+	}
+	return &reg, nil
+}
+
+// Save writes the registration file atomically
+func (s *FileRegistrationStore) Save(destination, application string, reg *Registration) error {
+	data, err := json.MarshalIndent(reg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode registration: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.Dir, "registration-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save registration: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save registration: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save registration: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path(destination, application)); err != nil {
+		return fmt.Errorf("failed to save registration: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the registration file
+func (s *FileRegistrationStore) Delete(destination, application string) error {
+	err := os.Remove(s.path(destination, application))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete registration: %w", err)
+	}
+	return nil
+}
+
+// WithRegistrationStore makes Register skip the REGISTER request when store
+// shows the destination already has the same application, notification
+// types, display names, enabled flags and icons. If the server later reports
+// the application or a notification as unknown, Notify registers again and
+// retries.
+func (c *Client) WithRegistrationStore(store RegistrationStore) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.registrationStore = store
+	return c
+}
+
+// registration describes what registering app would send
+func (c *Client) registration(app *Application) *Registration {
+	reg := &Registration{
+		Application:    app.Name,
+		IconMode:       c.IconMode,
+		CallbackTarget: c.callbackURL,
+		Types:          make([]RegisteredType, len(app.Notifications)),
+	}
+	if app.Icon != nil {
+		reg.Icon = app.Icon.Identifier
+	}
+	if app.Origin != nil {
+		reg.Origin = *app.Origin
+	}
+
+	l := c.localizer()
+	for i, notif := range app.Notifications {
+		reg.Types[i] = RegisteredType{
+			Name:        notif.Name,
+			DisplayName: l.displayName(notif),
+			Enabled:     notif.Enabled,
+		}
+		if notif.Icon != nil {
+			reg.Types[i].Icon = notif.Icon.Identifier
+		}
+	}
+	slices.SortFunc(reg.Types, func(a, b RegisteredType) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return reg
+}
+
+// sameAs reports whether reg registers the same things as other
+func (reg *Registration) sameAs(other *Registration) bool {
+	return other != nil &&
+		reg.Application == other.Application &&
+		reg.Icon == other.Icon &&
+		reg.IconMode == other.IconMode &&
+		reg.CallbackTarget == other.CallbackTarget &&
+		reg.Origin == other.Origin &&
+		slices.Equal(reg.Types, other.Types)
+}
+
+// unknownRegistration reports whether err means the server does not know
+// the application or notification, so it must be registered again
+func unknownRegistration(err error) bool {
+	code := ErrorCode(err)
+	return code == ErrorUnknownApplication || code == ErrorUnknownNotification
+}
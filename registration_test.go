@@ -0,0 +1,120 @@
+package gntp
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRegistrationStoreSkipsUnchanged(t *testing.T) {
+	icon := LoadResourceFromBytes(testPNG(t, 8, 8), "")
+	otherIcon := LoadResourceFromBytes(testPNG(t, 9, 9), "")
+
+	base := func() []*NotificationType {
+		return []*NotificationType{
+			NewNotificationType("build").WithDisplayName("Build").WithIcon(icon),
+			NewNotificationType("deploy").WithDisplayName("Deploy"),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		change func(types []*NotificationType) []*NotificationType
+		want   int // REGISTER requests sent for the changed types
+	}{
+		{"unchanged", func(types []*NotificationType) []*NotificationType { return types }, 0},
+		{"reordered", func(types []*NotificationType) []*NotificationType { return []*NotificationType{types[1], types[0]} }, 0},
+		{"display name", func(types []*NotificationType) []*NotificationType {
+			types[0].WithDisplayName("Builds")
+			return types
+		}, 1},
+		{"enabled", func(types []*NotificationType) []*NotificationType {
+			types[1].WithEnabled(false)
+			return types
+		}, 1},
+		{"icon", func(types []*NotificationType) []*NotificationType {
+			types[0].WithIcon(otherIcon)
+			return types
+		}, 1},
+		{"type added", func(types []*NotificationType) []*NotificationType {
+			return append(types, NewNotificationType("test"))
+		}, 1},
+		{"type removed", func(types []*NotificationType) []*NotificationType { return types[:1] }, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t)
+			store, err := NewFileRegistrationStore(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// A first run registers and records the registration
+			if err := server.client("Store").WithRegistrationStore(store).Register(base()); err != nil {
+				t.Fatal(err)
+			}
+			if got := len(server.received("REGISTER")); got != 1 {
+				t.Fatalf("first run sent %d REGISTER requests, want 1", got)
+			}
+
+			// A later run with the same store
+			client := server.client("Store").WithRegistrationStore(store)
+			if err := client.Register(tt.change(base())); err != nil {
+				t.Fatal(err)
+			}
+			if got := len(server.received("REGISTER")) - 1; got != tt.want {
+				t.Errorf("later run sent %d REGISTER requests, want %d", got, tt.want)
+			}
+
+			// Notify works either way
+			if err := client.Notify("build", "Done", ""); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestReregisterOnUnknownRegistration(t *testing.T) {
+	for _, code := range []int{ErrorUnknownApplication, ErrorUnknownNotification} {
+		t.Run(strconv.Itoa(code), func(t *testing.T) {
+			server := newFakeServer(t)
+			var notifies atomic.Int32
+			server.setRespond(func(req *fakeRequest) string {
+				if req.Type == "NOTIFY" && notifies.Add(1) == 1 {
+					return errorResponse(code, "forgotten")
+				}
+				return "GNTP/1.0 -OK NONE\r\nResponse-Action: " + req.Type + "\r\n\r\n"
+			})
+
+			store, err := NewFileRegistrationStore(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			types := []*NotificationType{NewNotificationType("build")}
+			if err := server.client("Store").WithRegistrationStore(store).Register(types); err != nil {
+				t.Fatal(err)
+			}
+
+			// The next run skips REGISTER, but the server has forgotten it
+			client := server.client("Store").WithRegistrationStore(store)
+			if err := client.Register(types); err != nil {
+				t.Fatal(err)
+			}
+			if err := client.Notify("build", "Done", ""); err != nil {
+				t.Fatalf("Notify = %v, want it retried after registering", err)
+			}
+
+			server.mu.Lock()
+			var sequence []string
+			for _, req := range server.requests {
+				sequence = append(sequence, req.Type)
+			}
+			server.mu.Unlock()
+			if got, want := strings.Join(sequence, " "), "REGISTER NOTIFY REGISTER NOTIFY"; got != want {
+				t.Errorf("requests = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package gntp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// RequestType is the message type of a GNTP request
+type RequestType string
+
+const (
+	// RequestRegister registers an application and its notification types
+	RequestRegister RequestType = "REGISTER"
+
+	// RequestNotify displays a notification
+	RequestNotify RequestType = "NOTIFY"
+)
+
+// Header is a single GNTP header
+type Header struct {
+	Name  string
+	Value string
+}
+
+// Headers is an ordered list of GNTP headers. Names are case-insensitive.
+type Headers []Header
+
+// Get returns the value of the first header with the given name
+func (h Headers) Get(name string) string {
+	for _, header := range h {
+		if strings.EqualFold(header.Name, name) {
+			return header.Value
+		}
+	}
+	return ""
+}
+
+// Has reports whether a header with the given name exists
+func (h Headers) Has(name string) bool {
+	for _, header := range h {
+		if strings.EqualFold(header.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Add appends a header
+func (h *Headers) Add(name, value string) {
+	*h = append(*h, Header{Name: name, Value: value})
+}
+
+// Set replaces the value of the first header with the given name, removes
+// any other header with that name, or appends the header if it is missing
+func (h *Headers) Set(name, value string) {
+	found := false
+	headers := (*h)[:0]
+	for _, header := range *h {
+		if strings.EqualFold(header.Name, name) {
+			if found {
+				continue
+			}
+			header.Value = value
+			found = true
+		}
+		headers = append(headers, header)
+	}
+	*h = headers
+
+	if !found {
+		h.Add(name, value)
+	}
+}
+
+// Del removes all headers with the given name
+func (h *Headers) Del(name string) {
+	headers := (*h)[:0]
+	for _, header := range *h {
+		if !strings.EqualFold(header.Name, name) {
+			headers = append(headers, header)
+		}
+	}
+	*h = headers
+}
+
+// writeTo writes the headers in GNTP wire format
+func (h Headers) writeTo(w io.Writer) error {
+	for _, header := range h {
+		// A CR in a value would end the header early
+		if _, err := fmt.Fprintf(w, "%s: %s%s", header.Name, normalizeLineEndings(header.Value), CRLF); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Request is a GNTP request on its way to the server
+type Request struct {
+	Type      RequestType
+	Headers   Headers     // Application and notification headers
+	Sections  []Headers   // Notification type blocks of a REGISTER request
+	Resources []*Resource // Binary resources sent after the headers
+
+	autoIcons bool // IconModeAuto referenced an icon other than by data URL
+}
+
+// addResource attaches a binary resource unless it is nil or a resource with
+// the same identifier is already attached
+func (r *Request) addResource(res *Resource) {
+	if res == nil {
+		return
+	}
+	for _, attached := range r.Resources {
+		if attached.Identifier == res.Identifier {
+			return
+		}
+	}
+	r.Resources = append(r.Resources, res)
+}
+
+// writeTo writes the text part of the request; binary resources are
+// written separately by writeResource
+func (r *Request) writeTo(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "GNTP/%s %s NONE%s", GNTPVersion, r.Type, CRLF); err != nil {
+		return err
+	}
+	if err := r.Headers.writeTo(w); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, CRLF); err != nil {
+		return err
+	}
+
+	for _, section := range r.Sections {
+		if err := section.writeTo(w); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, CRLF); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encode returns the text part of the request, for logging
+func (r *Request) encode() string {
+	var packet strings.Builder
+	r.writeTo(&packet)
+	return packet.String()
+}
+
+// Response is a parsed GNTP response
+type Response struct {
+	Status  string  // "OK", "ERROR" or "CALLBACK"
+	Headers Headers // Response headers
+	Raw     string  // Response as received
+}
+
+// parseResponse parses the text of a GNTP response
+func parseResponse(raw string) *Response {
+	resp := &Response{Raw: raw}
+
+	lines := strings.Split(strings.ReplaceAll(raw, CRLF, "\n"), "\n")
+	if len(lines) > 0 {
+		// GNTP/1.0 -OK NONE
+		fields := strings.Fields(lines[0])
+		if len(fields) >= 2 {
+			resp.Status = strings.TrimPrefix(fields[1], "-")
+		}
+		lines = lines[1:]
+	}
+
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		resp.Headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	return resp
+}
+
+// Sender delivers a request to a GNTP server
+type Sender interface {
+	Send(ctx context.Context, req *Request) (*Response, error)
+}
+
+// SenderFunc adapts a function to the Sender interface
+type SenderFunc func(ctx context.Context, req *Request) (*Response, error)
+
+// Send calls f(ctx, req)
+func (f SenderFunc) Send(ctx context.Context, req *Request) (*Response, error) {
+	return f(ctx, req)
+}
+
+// Interceptor wraps a Sender to inspect or modify requests and responses.
+// An interceptor may short-circuit delivery by returning without calling next.
+type Interceptor func(next Sender) Sender
+
+// Use appends interceptors to the chain run by Register and Notify.
+// The first interceptor added is the outermost.
+func (c *Client) Use(interceptors ...Interceptor) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.interceptors = append(c.interceptors, interceptors...)
+	return c
+}
+
+// send runs req through the interceptor chain and the transport
+func (c *Client) send(ctx context.Context, req *Request) (*Response, error) {
+	c.mu.Lock()
+	interceptors := c.interceptors
+	c.mu.Unlock()
+
+	tracer := c.tracing()
+	ctx, span := tracer.Start(ctx, "gntp."+strings.ToLower(string(req.Type)),
+		slog.String("gntp.destination", c.address()),
+	)
+	defer span.End()
+
+	if req.Type == RequestNotify {
+		if traceParent := tracer.Inject(ctx); traceParent != "" {
+			req.Headers.Set(TraceHeader, traceParent)
+		}
+	}
+
+	var sender Sender = SenderFunc(c.transport)
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		sender = interceptors[i](sender)
+	}
+
+	resp, err := sender.Send(ctx, req)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return resp, err
+}
@@ -0,0 +1,157 @@
+package gntp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestHeaders(t *testing.T) {
+	var h Headers
+	h.Add("Notification-Title", "one")
+	h.Add("X-Custom", "a")
+	h.Add("x-custom", "b")
+
+	if got := h.Get("NOTIFICATION-TITLE"); got != "one" {
+		t.Errorf("Get = %q, want case-insensitive match", got)
+	}
+	if got := h.Get("X-Custom"); got != "a" {
+		t.Errorf("Get = %q, want the first header", got)
+	}
+
+	h.Set("x-CUSTOM", "c")
+	want := Headers{{"Notification-Title", "one"}, {"X-Custom", "c"}}
+	if !reflect.DeepEqual(h, want) {
+		t.Errorf("after Set = %v, want %v", h, want)
+	}
+
+	h.Set("Notification-Text", "two")
+	if !h.Has("notification-text") || h[len(h)-1].Name != "Notification-Text" {
+		t.Errorf("Set of a missing header = %v, want it appended", h)
+	}
+
+	h.Del("x-custom")
+	if h.Has("X-Custom") {
+		t.Errorf("after Del = %v", h)
+	}
+}
+
+func TestInterceptorOrder(t *testing.T) {
+	server := newFakeServer(t)
+	client := server.client("Intercept")
+
+	var mu sync.Mutex
+	var calls []string
+	record := func(name string) Interceptor {
+		return func(next Sender) Sender {
+			return SenderFunc(func(ctx context.Context, req *Request) (*Response, error) {
+				mu.Lock()
+				calls = append(calls, name+" "+string(req.Type))
+				mu.Unlock()
+
+				resp, err := next.Send(ctx, req)
+
+				mu.Lock()
+				calls = append(calls, name+" "+resp.Status)
+				mu.Unlock()
+				return resp, err
+			})
+		}
+	}
+	client.Use(record("outer")).Use(record("inner"))
+
+	if err := client.Register([]*NotificationType{NewNotificationType("job")}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"outer REGISTER", "inner REGISTER", "inner OK", "outer OK"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %q, want %q", calls, want)
+	}
+}
+
+func TestInterceptorModifiesRequest(t *testing.T) {
+	server := newFakeServer(t)
+	client := server.client("Intercept")
+
+	attachment := LoadResourceFromBytes(testPNG(t, 4, 4), "")
+	client.Use(func(next Sender) Sender {
+		return SenderFunc(func(ctx context.Context, req *Request) (*Response, error) {
+			if req.Type == RequestNotify {
+				req.Headers.Set("Notification-Title", "[prod] "+req.Headers.Get("Notification-Title"))
+				req.Headers.Del("Notification-Text")
+				req.Headers.Add("X-Attachment", "x-growl-resource://"+attachment.Identifier)
+				req.Resources = append(req.Resources, attachment)
+			}
+			return next.Send(ctx, req)
+		})
+	})
+
+	if err := client.Register([]*NotificationType{NewNotificationType("job")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Notify("job", "Deployed", "v1.2"); err != nil {
+		t.Fatal(err)
+	}
+
+	notify := server.received("NOTIFY")
+	if len(notify) != 1 {
+		t.Fatalf("received %d notifications, want 1", len(notify))
+	}
+	req := notify[0]
+	if got := req.Headers["Notification-Title"]; got != "[prod] Deployed" {
+		t.Errorf("title = %q, want the interceptor's", got)
+	}
+	if _, ok := req.Headers["Notification-Text"]; ok {
+		t.Error("deleted Notification-Text was sent")
+	}
+	if got := req.Resources[attachment.Identifier]; !bytes.Equal(got, attachment.Data) {
+		t.Errorf("attached resource = %d bytes, want %d", len(got), len(attachment.Data))
+	}
+}
+
+func TestInterceptorShortCircuits(t *testing.T) {
+	errBlocked := errors.New("blocked")
+
+	tests := []struct {
+		name string
+		resp *Response
+		err  error
+	}{
+		{"response", &Response{Status: "OK"}, nil},
+		{"error", nil, errBlocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t)
+			client := server.client("Intercept")
+
+			var reachedNext bool
+			client.Use(func(next Sender) Sender {
+				return SenderFunc(func(ctx context.Context, req *Request) (*Response, error) {
+					return tt.resp, tt.err
+				})
+			}, func(next Sender) Sender {
+				return SenderFunc(func(ctx context.Context, req *Request) (*Response, error) {
+					reachedNext = true
+					return next.Send(ctx, req)
+				})
+			})
+
+			err := client.Register([]*NotificationType{NewNotificationType("job")})
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Register = %v, want %v", err, tt.err)
+			}
+			if reachedNext {
+				t.Error("interceptor after the short circuit ran")
+			}
+			if got := len(server.received("REGISTER")); got != 0 {
+				t.Errorf("server received %d requests", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package gntp
+
+import (
+	"context"
+	"sync"
+)
+
+// ResourceCache remembers which binary resources each destination has
+// already received, so IconModeBinary requests can reference a resource by
+// its x-growl-resource:// identifier without sending the bytes again.
+// A cache may be shared by several clients.
+type ResourceCache struct {
+	mu   sync.Mutex
+	sent map[string]map[string]bool // destination -> resource identifiers
+}
+
+// NewResourceCache creates an empty cache
+func NewResourceCache() *ResourceCache {
+	return &ResourceCache{sent: make(map[string]map[string]bool)}
+}
+
+// Has reports whether destination has received the resource
+func (rc *ResourceCache) Has(destination, identifier string) bool {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	return rc.sent[destination][identifier]
+}
+
+// Add records that destination has received the resources
+func (rc *ResourceCache) Add(destination string, identifiers ...string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	ids, ok := rc.sent[destination]
+	if !ok {
+		ids = make(map[string]bool)
+		rc.sent[destination] = ids
+	}
+	for _, id := range identifiers {
+		ids[id] = true
+	}
+}
+
+// Forget discards everything recorded for destination
+func (rc *ResourceCache) Forget(destination string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	delete(rc.sent, destination)
+}
+
+// WithResourceCache enables skipping binary resources the server already
+// has. Only use it with servers that keep resources across requests; if the
+// server rejects a request with an error that may mean a resource is
+// missing, it is resent once with all resources.
+func (c *Client) WithResourceCache(cache *ResourceCache) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.resourceCache = cache
+	return c
+}
+
+// sendCached sends req without the binary resources the destination already
+// has, falling back to sending all of them if the server rejects the request
+// in a way a missing resource could explain
+func (c *Client) sendCached(ctx context.Context, req *Request) (*Response, error) {
+	c.mu.Lock()
+	cache := c.resourceCache
+	c.mu.Unlock()
+
+	if cache == nil || len(req.Resources) == 0 {
+		return c.send(ctx, req)
+	}
+
+	destination := c.address()
+	all := req.Resources
+	missing := make([]*Resource, 0, len(all))
+	for _, res := range all {
+		if !cache.Has(destination, res.Identifier) {
+			missing = append(missing, res)
+		}
+	}
+
+	req.Resources = missing
+	resp, err := c.send(ctx, req)
+	if err == nil {
+		cache.Add(destination, resourceIDs(missing)...)
+		return resp, nil
+	}
+
+	// The same codes a badly referenced icon produces
+	if len(missing) == len(all) || !iconFallbackCode(ErrorCode(err)) {
+		return nil, err
+	}
+
+	// The server may have discarded its copies: send everything again
+	cache.Forget(destination)
+	req.Resources = all
+	resp, err = c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	cache.Add(destination, resourceIDs(all)...)
+	return resp, nil
+}
+
+// resourceIDs returns the identifiers of resources
+func resourceIDs(resources []*Resource) []string {
+	ids := make([]string, len(resources))
+	for i, res := range resources {
+		ids[i] = res.Identifier
+	}
+	return ids
+}
@@ -0,0 +1,98 @@
+package gntp
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestResourceCacheSkipsSentResources(t *testing.T) {
+	server := newFakeServer(t)
+	cache := NewResourceCache()
+	client := server.client("Cache").WithIconMode(IconModeBinary).WithResourceCache(cache)
+	icon := LoadResourceFromBytes(testPNG(t, 8, 8), "")
+
+	if err := client.Register([]*NotificationType{NewNotificationType("job")}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := client.NotifyWithOptions("job", "Title", "Text", NewNotifyOptions().WithIcon(icon)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	notify := server.received("NOTIFY")
+	if len(notify) != 2 {
+		t.Fatalf("received %d notifications, want 2", len(notify))
+	}
+	if _, ok := notify[0].Resources[icon.Identifier]; !ok {
+		t.Error("first notification did not carry the icon")
+	}
+	if len(notify[1].Resources) != 0 {
+		t.Error("second notification sent the icon again")
+	}
+	if got := notify[1].Headers["Notification-Icon"]; got != "x-growl-resource://"+icon.Identifier {
+		t.Errorf("second notification icon = %q, want a reference to the sent resource", got)
+	}
+}
+
+func TestResourceCacheResend(t *testing.T) {
+	tests := []struct {
+		code       int
+		wantResend bool
+	}{
+		{ErrorInvalidRequest, true},
+		{ErrorRequiredHeaderMissing, true},
+		{ErrorInternalServerError, true},
+		{ErrorUnknownNotification, false},
+		{ErrorNotAuthorized, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.code), func(t *testing.T) {
+			server := newFakeServer(t)
+			cache := NewResourceCache()
+			client := server.client("Cache").WithIconMode(IconModeBinary).WithResourceCache(cache)
+			icon := LoadResourceFromBytes(testPNG(t, 8, 8), "")
+			options := NewNotifyOptions().WithIcon(icon)
+
+			if err := client.Register([]*NotificationType{NewNotificationType("job")}); err != nil {
+				t.Fatal(err)
+			}
+			if err := client.NotifyWithOptions("job", "Title", "Text", options); err != nil {
+				t.Fatal(err)
+			}
+
+			// Reject the next request that references the icon without sending it
+			var rejected atomic.Bool
+			server.setRespond(func(req *fakeRequest) string {
+				if len(req.Resources) == 0 && !rejected.Swap(true) {
+					return errorResponse(tt.code, "rejected")
+				}
+				return "GNTP/1.0 -OK NONE\r\nResponse-Action: " + req.Type + "\r\n\r\n"
+			})
+
+			err := client.NotifyWithOptions("job", "Title", "Text", options)
+			notify := server.received("NOTIFY")
+			if tt.wantResend {
+				if err != nil {
+					t.Fatalf("Notify = %v, want it resent with the icon", err)
+				}
+				if len(notify) != 3 || len(notify[2].Resources) != 1 {
+					t.Errorf("received %d notifications, want a third carrying the icon", len(notify))
+				}
+				return
+			}
+
+			if ErrorCode(err) != tt.code {
+				t.Errorf("Notify = %v, want error %d", err, tt.code)
+			}
+			if len(notify) != 2 {
+				t.Errorf("received %d notifications, want no resend", len(notify))
+			}
+			if !cache.Has(client.address(), icon.Identifier) {
+				t.Error("an unrelated error cleared the resource cache")
+			}
+		})
+	}
+}
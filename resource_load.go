@@ -0,0 +1,109 @@
+package gntp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var (
+	// MaxResourceSize limits how many bytes LoadResourceReader and
+	// LoadResourceURL read
+	MaxResourceSize int64 = 10 << 20
+
+	// ResourceFetchTimeout limits how long LoadResourceURL waits for a download
+	ResourceFetchTimeout = 30 * time.Second
+)
+
+// ErrResourceTooLarge is returned when resource data exceeds MaxResourceSize
+var ErrResourceTooLarge = errors.New("gntp: resource too large")
+
+// LoadResourceFS loads an icon from a file system such as an embed.FS. The
+// resource has no SourcePath, so IconModeFileURL falls back to a data URL.
+func LoadResourceFS(fsys fs.FS, name string) (*Resource, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	r, err := NewResource(data, "")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return r, nil
+}
+
+// LoadResourceReader loads an icon from r, reading at most MaxResourceSize
+// bytes. An empty mimeType is detected from the data; otherwise it must
+// match the content.
+func LoadResourceReader(r io.Reader, mimeType string) (*Resource, error) {
+	data, err := readLimited(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewResource(data, mimeType)
+}
+
+// LoadResourceURL downloads an icon over HTTP or HTTPS, reading at most
+// MaxResourceSize bytes within ResourceFetchTimeout. The URL is kept as
+// SourcePath so IconModeHttpURL can reference it instead of sending the data.
+func LoadResourceURL(ctx context.Context, rawURL string) (*Resource, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resource URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported resource URL scheme %q", u.Scheme)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ResourceFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch resource: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch resource: %s", resp.Status)
+	}
+	if resp.ContentLength > MaxResourceSize {
+		return nil, fmt.Errorf("%w: %d bytes exceeds %d", ErrResourceTooLarge, resp.ContentLength, MaxResourceSize)
+	}
+
+	data, err := readLimited(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Servers often mislabel images, so the content decides the type
+	r, err := NewResource(data, "")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", rawURL, err)
+	}
+	r.SourcePath = u.String()
+	return r, nil
+}
+
+// readLimited reads r to the end, failing if it holds more than MaxResourceSize bytes
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxResourceSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resource: %w", err)
+	}
+	if int64(len(data)) > MaxResourceSize {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrResourceTooLarge, MaxResourceSize)
+	}
+	return data, nil
+}
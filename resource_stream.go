@@ -0,0 +1,206 @@
+package gntp
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// resourceStream is the content of a resource that is read each time it is
+// needed instead of held in Data
+type resourceStream struct {
+	open func() (io.ReadCloser, error)
+	size int64
+}
+
+// sectionReadCloser is a seekable reader over a fixed range that needs no closing
+type sectionReadCloser struct {
+	*io.SectionReader
+}
+
+func (sectionReadCloser) Close() error { return nil }
+
+// NewResourceReaderAt creates a resource whose size bytes are read from r
+// when sent, so the icon is never held in memory as a whole. r must not
+// change while the resource is in use. An empty mimeType is detected from
+// the content; otherwise it must match it.
+func NewResourceReaderAt(r io.ReaderAt, size int64, mimeType string) (*Resource, error) {
+	return NewResourceOpener(func() (io.ReadCloser, error) {
+		return sectionReadCloser{io.NewSectionReader(r, 0, size)}, nil
+	}, size, mimeType)
+}
+
+// NewResourceOpener creates a resource whose content is read from a reader
+// returned by open each time it is sent. Each reader must yield at least
+// size bytes of the same content. An empty mimeType is detected from the
+// content; otherwise it must match it.
+func NewResourceOpener(open func() (io.ReadCloser, error), size int64, mimeType string) (*Resource, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("gntp: invalid resource size %d", size)
+	}
+
+	r := &Resource{
+		MimeType: mimeType,
+		stream:   &resourceStream{open: open, size: size},
+	}
+
+	identifier, head, err := r.scan()
+	if err != nil {
+		return nil, err
+	}
+	r.Identifier = identifier
+
+	if r.MimeType == "" {
+		if r.MimeType = SniffMimeType(head); r.MimeType == "" {
+			return nil, ErrNotImage
+		}
+	} else if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// LoadResourceStream loads an icon from a file that is read again each time
+// it is sent instead of being kept in memory. The MIME type is detected from
+// the content.
+func LoadResourceStream(path string) (*Resource, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	r, err := NewResourceOpener(func() (io.ReadCloser, error) {
+		return os.Open(path)
+	}, info.Size(), "")
+	if errors.Is(err, ErrNotImage) {
+		return nil, fmt.Errorf("%s: %w", path, ErrNotImage)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.SourcePath = path
+	return r, nil
+}
+
+// scan reads the content once, returning its identifier and first bytes
+func (r *Resource) scan() (string, []byte, error) {
+	content, err := r.Open()
+	if err != nil {
+		return "", nil, err
+	}
+	defer content.Close()
+
+	head := make([]byte, min(r.Size(), sniffLength))
+	if _, err := io.ReadFull(content, head); err != nil {
+		return "", nil, fmt.Errorf("failed to read resource: %w", err)
+	}
+
+	hash := md5.New()
+	hash.Write(head)
+	if _, err := io.CopyN(hash, content, r.Size()-int64(len(head))); err != nil {
+		return "", nil, fmt.Errorf("failed to read resource: %w", err)
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil)), head, nil
+}
+
+// Size returns the length of the resource content
+func (r *Resource) Size() int64 {
+	if r.stream != nil {
+		return r.stream.size
+	}
+	return int64(len(r.Data))
+}
+
+// Open returns a reader over the resource content. For resources in memory
+// and those created with NewResourceReaderAt, it is an io.ReadSeeker.
+func (r *Resource) Open() (io.ReadCloser, error) {
+	if r.stream == nil {
+		return sectionReadCloser{io.NewSectionReader(bytes.NewReader(r.Data), 0, int64(len(r.Data)))}, nil
+	}
+
+	content, err := r.stream.open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open resource: %w", err)
+	}
+	return content, nil
+}
+
+// content returns the whole resource content, reading a streamed resource
+// into memory
+func (r *Resource) content() ([]byte, error) {
+	if r.stream == nil {
+		return r.Data, nil
+	}
+
+	content, err := r.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	data := make([]byte, r.stream.size)
+	if _, err := io.ReadFull(content, data); err != nil {
+		return nil, fmt.Errorf("failed to read resource: %w", err)
+	}
+	return data, nil
+}
+
+// head returns up to n bytes from the start of the content
+func (r *Resource) head(n int64) ([]byte, error) {
+	if r.stream == nil {
+		return r.Data[:min(int64(len(r.Data)), n)], nil
+	}
+
+	content, err := r.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	data := make([]byte, min(r.stream.size, n))
+	if _, err := io.ReadFull(content, data); err != nil {
+		return nil, fmt.Errorf("failed to read resource: %w", err)
+	}
+	return data, nil
+}
+
+// writeResource writes a binary resource block: identifier, length, the
+// content streamed from the resource and the closing blank line
+func writeResource(w io.Writer, res *Resource) error {
+	if _, err := io.WriteString(w, resourceHeader(res)); err != nil {
+		return fmt.Errorf("failed to send resource header: %w", err)
+	}
+
+	content, err := res.Open()
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	if _, err := io.CopyN(w, content, res.Size()); err != nil {
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to send resource data: content shorter than %d bytes", res.Size())
+		}
+		return fmt.Errorf("failed to send resource data: %w", err)
+	}
+	if _, err := io.WriteString(w, CRLF+CRLF); err != nil {
+		return fmt.Errorf("failed to send resource CRLF: %w", err)
+	}
+	return nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,121 @@
+package gntp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestNewResourceReaderAtChecksMimeType(t *testing.T) {
+	data := testPNG(t, 8, 8)
+
+	tests := []struct {
+		mimeType string
+		wantErr  bool
+	}{
+		{"", false},
+		{"image/png", false},
+		{"image/x-png", false},
+		{"image/jpeg", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mimeType, func(t *testing.T) {
+			r, err := NewResourceReaderAt(bytes.NewReader(data), int64(len(data)), tt.mimeType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewResourceReaderAt(%q) = %v, want error %v", tt.mimeType, err, tt.wantErr)
+			}
+			if err == nil && r.Identifier != ResourceID(data) {
+				t.Errorf("Identifier = %s, want %s", r.Identifier, ResourceID(data))
+			}
+		})
+	}
+
+	if _, err := NewResourceReaderAt(strings.NewReader("not an image"), 12, ""); !errors.Is(err, ErrNotImage) {
+		t.Errorf("NewResourceReaderAt(text) = %v, want ErrNotImage", err)
+	}
+}
+
+func TestStreamedIconsInBinaryMode(t *testing.T) {
+	server := newFakeServer(t)
+	client := server.client("Stream").WithIconMode(IconModeBinary)
+
+	first, second := testPNG(t, 40, 40), testPNG(t, 70, 30)
+	firstIcon, err := NewResourceReaderAt(bytes.NewReader(first), int64(len(first)), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondIcon, err := NewResourceReaderAt(bytes.NewReader(second), int64(len(second)), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.Register([]*NotificationType{
+		NewNotificationType("a").WithIcon(firstIcon),
+		NewNotificationType("b").WithIcon(secondIcon),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registers := server.received("REGISTER")
+	if len(registers) != 1 {
+		t.Fatalf("received %d REGISTER requests, want 1", len(registers))
+	}
+	req := registers[0]
+
+	for _, icon := range []struct {
+		resource *Resource
+		data     []byte
+	}{{firstIcon, first}, {secondIcon, second}} {
+		if got := req.Resources[icon.resource.Identifier]; !bytes.Equal(got, icon.data) {
+			t.Errorf("resource %s: received %d bytes, want the %d bytes of the icon", icon.resource.Identifier, len(got), len(icon.data))
+		}
+
+		block := fmt.Sprintf("Identifier: %s\r\nLength: %d\r\n\r\n", icon.resource.Identifier, len(icon.data))
+		framed := append([]byte(block), icon.data...)
+		framed = append(framed, "\r\n\r\n"...)
+		if !bytes.Contains(req.Raw, framed) {
+			t.Errorf("resource %s is not framed by its Identifier and Length headers and a blank line", icon.resource.Identifier)
+		}
+	}
+	if !bytes.HasSuffix(req.Raw, []byte("\r\n\r\n")) {
+		t.Error("request does not end with a blank line")
+	}
+}
+
+func TestStreamedIconShorterThanSize(t *testing.T) {
+	data := testPNG(t, 40, 40)
+	if _, err := NewResourceReaderAt(bytes.NewReader(data), int64(len(data))+10, ""); err == nil {
+		t.Error("NewResourceReaderAt with a size past the content succeeded")
+	}
+
+	// The content shrinks after the resource is created
+	var opens atomic.Int32
+	icon, err := NewResourceOpener(func() (io.ReadCloser, error) {
+		if opens.Add(1) == 1 {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		}
+		return io.NopCloser(bytes.NewReader(data[:len(data)/2])), nil
+	}, int64(len(data)), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := newFakeServer(t)
+	client := server.client("Stream").WithIconMode(IconModeBinary)
+	if err := client.Register([]*NotificationType{NewNotificationType("a")}); err != nil {
+		t.Fatal(err)
+	}
+	err = client.NotifyWithOptions("a", "Title", "Text", NewNotifyOptions().WithIcon(icon))
+	if err == nil || !strings.Contains(err.Error(), "content shorter than") {
+		t.Errorf("Notify with shrunk content = %v, want a short content error", err)
+	}
+	if got := len(server.received("NOTIFY")); got != 0 {
+		t.Errorf("server accepted %d truncated requests", got)
+	}
+}
@@ -0,0 +1,130 @@
+package gntp
+
+import (
+	"net"
+	"os"
+	"strings"
+)
+
+// ServerInfo identifies a GNTP server from the Origin- headers of its responses
+type ServerInfo struct {
+	SoftwareName    string // Origin-Software-Name, e.g. "Growl/Win"
+	SoftwareVersion string // Origin-Software-Version
+	PlatformName    string // Origin-Platform-Name
+	PlatformVersion string // Origin-Platform-Version
+	MachineName     string // Origin-Machine-Name
+}
+
+// destinationState is what the client learned about one server
+type destinationState struct {
+	info            *ServerInfo
+	dataURLFallback bool // a non-data-URL icon request was rejected
+}
+
+// ServerInfo returns what the current destination reported about itself,
+// or nil before the first response
+func (c *Client) ServerInfo() *ServerInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if state, ok := c.destinations[c.address()]; ok && state.info != nil {
+		info := *state.info
+		return &info
+	}
+	return nil
+}
+
+// destination returns the state of the current destination; c.mu must be held
+func (c *Client) destination() *destinationState {
+	if c.destinations == nil {
+		c.destinations = make(map[string]*destinationState)
+	}
+
+	address := c.address()
+	state, ok := c.destinations[address]
+	if !ok {
+		state = &destinationState{}
+		c.destinations[address] = state
+	}
+	return state
+}
+
+// recordServerInfo remembers the identity the server sent in resp
+func (c *Client) recordServerInfo(resp *Response) {
+	name := resp.Headers.Get("Origin-Software-Name")
+	if name == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.destination().info = &ServerInfo{
+		SoftwareName:    name,
+		SoftwareVersion: resp.Headers.Get("Origin-Software-Version"),
+		PlatformName:    resp.Headers.Get("Origin-Platform-Name"),
+		PlatformVersion: resp.Headers.Get("Origin-Platform-Version"),
+		MachineName:     resp.Headers.Get("Origin-Machine-Name"),
+	}
+}
+
+// autoIconMode picks the icon mode for icon in IconModeAuto: binary if the
+// server's profile uses it, the icon's URL if it has one, a file URL when
+// Growl runs on this machine and the file exists, the icon server if one is
+// running, and a data URL otherwise
+func (c *Client) autoIconMode(icon *Resource) IconMode {
+	c.mu.Lock()
+	state := c.destination()
+	fallback := state.dataURLFallback
+	c.mu.Unlock()
+
+	profile := c.profile()
+
+	switch {
+	case fallback:
+		return IconModeDataURL
+	case profile != nil && profile.IconMode == IconModeBinary:
+		return IconModeBinary
+	case icon.isURL():
+		return IconModeHttpURL
+	case icon.SourcePath != "" && c.isLocal() && fileExists(icon.SourcePath):
+		return IconModeFileURL
+	case c.servedIcons() != nil:
+		return IconModeHttpURL
+	default:
+		return IconModeDataURL
+	}
+}
+
+// isLocal reports whether the Growl server runs on this machine
+func (c *Client) isLocal() bool {
+	if strings.EqualFold(c.Host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(c.Host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// fileExists reports whether path is an existing regular file
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// fallBackToDataURL makes IconModeAuto use data URLs for the current destination
+func (c *Client) fallBackToDataURL() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.destination().dataURLFallback = true
+}
+
+// iconFallbackCode reports whether a server error code may be caused by the
+// way icons were referenced, so retrying with data URLs could succeed
+func iconFallbackCode(code int) bool {
+	switch code {
+	case ErrorInvalidRequest, ErrorRequiredHeaderMissing, ErrorInternalServerError:
+		return true
+	}
+	return false
+}
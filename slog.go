@@ -0,0 +1,163 @@
+package gntp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Notification type names used by SlogHandler
+const (
+	SlogNotificationDebug = "log-debug"
+	SlogNotificationInfo  = "log-info"
+	SlogNotificationWarn  = "log-warn"
+	SlogNotificationError = "log-error"
+)
+
+// SlogHandlerOptions configures a SlogHandler
+type SlogHandlerOptions struct {
+	// Level is the minimum level that produces a notification.
+	// Defaults to slog.LevelWarn.
+	Level slog.Leveler
+}
+
+// SlogHandler is a slog.Handler that turns log records into notifications.
+// Records are queued for the client's async workers without ever waiting:
+// when the queue is full they are dropped (or replace the oldest with
+// OverflowDropOldest) and counted in AsyncStats.Dropped.
+// The client must have registered SlogNotificationTypes before logging, and
+// must not itself log through this handler, or failures would loop.
+type SlogHandler struct {
+	client *Client
+	level  slog.Leveler
+	attrs  []string // Preformatted "key=value" pairs from WithAttrs
+	group  string   // Prefix for keys, from WithGroup
+}
+
+// SlogNotificationTypes returns the notification types used by SlogHandler.
+// Register them together with the application's own types.
+func SlogNotificationTypes() []*NotificationType {
+	return []*NotificationType{
+		NewNotificationType(SlogNotificationDebug).WithDisplayName("Debug"),
+		NewNotificationType(SlogNotificationInfo).WithDisplayName("Info"),
+		NewNotificationType(SlogNotificationWarn).WithDisplayName("Warning"),
+		NewNotificationType(SlogNotificationError).WithDisplayName("Error"),
+	}
+}
+
+// NewSlogHandler creates a handler sending log records through client
+func NewSlogHandler(client *Client, options *SlogHandlerOptions) *SlogHandler {
+	var level slog.Leveler = slog.LevelWarn
+	if options != nil && options.Level != nil {
+		level = options.Level
+	}
+	return &SlogHandler{
+		client: client,
+		level:  level,
+	}
+}
+
+// Enabled reports whether records at level produce notifications
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle queues a notification for the record, dropping it if the queue is
+// full. The message becomes the title and the attributes the text, one
+// "key=value" per line. Errors are sticky.
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	lines := make([]string, 0, len(h.attrs)+r.NumAttrs())
+	lines = append(lines, h.attrs...)
+	r.Attrs(func(attr slog.Attr) bool {
+		lines = appendAttr(lines, h.group, attr)
+		return true
+	})
+
+	options := NewNotifyOptions().
+		WithPriority(levelPriority(r.Level)).
+		WithSticky(r.Level >= slog.LevelError)
+
+	err := h.client.notifyAsync(levelNotification(r.Level), r.Message, strings.Join(lines, "\n"), options, true)
+	if errors.Is(err, ErrQueueFull) {
+		// Dropped: logging must not stall the application
+		return nil
+	}
+	return err
+}
+
+// WithAttrs returns a handler that includes attrs in every notification
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = make([]string, len(h.attrs), len(h.attrs)+len(attrs))
+	copy(h2.attrs, h.attrs)
+	for _, attr := range attrs {
+		h2.attrs = appendAttr(h2.attrs, h.group, attr)
+	}
+	return &h2
+}
+
+// WithGroup returns a handler that qualifies later attribute keys with name
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.group = h.group + name + "."
+	return &h2
+}
+
+// appendAttr formats attr as "key=value" lines, flattening groups
+func appendAttr(lines []string, prefix string, attr slog.Attr) []string {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return lines
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindGroup:
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, a := range attr.Value.Group() {
+			lines = appendAttr(lines, prefix, a)
+		}
+		return lines
+
+	case slog.KindTime:
+		return append(lines, fmt.Sprintf("%s%s=%s", prefix, attr.Key, attr.Value.Time().Format(time.RFC3339)))
+
+	default:
+		return append(lines, fmt.Sprintf("%s%s=%s", prefix, attr.Key, attr.Value.String()))
+	}
+}
+
+// levelPriority maps a log level to a notification priority
+func levelPriority(level slog.Level) int {
+	switch {
+	case level >= slog.LevelError:
+		return 2
+	case level >= slog.LevelWarn:
+		return 1
+	case level >= slog.LevelInfo:
+		return 0
+	default:
+		return -2
+	}
+}
+
+// levelNotification maps a log level to a notification type name
+func levelNotification(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return SlogNotificationError
+	case level >= slog.LevelWarn:
+		return SlogNotificationWarn
+	case level >= slog.LevelInfo:
+		return SlogNotificationInfo
+	default:
+		return SlogNotificationDebug
+	}
+}
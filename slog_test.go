@@ -0,0 +1,56 @@
+package gntp
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestSlogHandlerSendsRecords(t *testing.T) {
+	server := newFakeServer(t)
+	client := server.client("Logger")
+	if err := client.Register(SlogNotificationTypes()); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(NewSlogHandler(client, nil)).With("service", "api")
+	logger.Info("ignored")
+	logger.Error("backup failed", slog.Group("disk", "name", "sdb"), "attempt", 3)
+	if err := client.Shutdown(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	received := server.received("NOTIFY")
+	if len(received) != 1 {
+		t.Fatalf("received %d notifications, want 1", len(received))
+	}
+	headers := received[0].Headers
+	if headers["Notification-Name"] != SlogNotificationError || headers["Notification-Priority"] != "2" || headers["Notification-Sticky"] != "True" {
+		t.Errorf("headers = %v", headers)
+	}
+	if want := "service=api\ndisk.name=sdb\nattempt=3"; headers["Notification-Text"] != want {
+		t.Errorf("text = %q, want %q", headers["Notification-Text"], want)
+	}
+}
+
+func TestSlogHandlerNeverBlocks(t *testing.T) {
+	server := newFakeServer(t)
+	client := server.client("Logger")
+	if err := client.Register(SlogNotificationTypes()); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	server.setDelay(10 * time.Second)
+
+	logger := slog.New(NewSlogHandler(client, nil))
+	start := time.Now()
+	for i := 0; i < DefaultAsyncQueueSize+5; i++ {
+		logger.Error("disk full")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("logging blocked for %v", elapsed)
+	}
+	if stats := client.AsyncStats(); stats.Dropped == 0 {
+		t.Error("no record was dropped from the full queue")
+	}
+}
@@ -0,0 +1,139 @@
+package gntp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// sniffLength is how much of the data is inspected to detect its type
+const sniffLength = 1024
+
+// ErrNotImage is returned when resource data is not a supported image
+var ErrNotImage = errors.New("gntp: resource is not a supported image")
+
+// mimeAliases maps non-canonical image MIME types to the ones SniffMimeType returns
+var mimeAliases = map[string]string{
+	"image/jpg":                "image/jpeg",
+	"image/pjpeg":              "image/jpeg",
+	"image/x-png":              "image/png",
+	"image/x-ms-bmp":           "image/bmp",
+	"image/x-bmp":              "image/bmp",
+	"image/vnd.microsoft.icon": "image/x-icon",
+	"image/ico":                "image/x-icon",
+}
+
+// SniffMimeType detects the image type of data from its content. It
+// recognizes PNG, JPEG, GIF, BMP, ICO, WebP and SVG and returns "" for
+// anything else.
+func SniffMimeType(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return "image/png"
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return "image/jpeg"
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return "image/gif"
+	case len(data) >= 14 && bytes.HasPrefix(data, []byte("BM")):
+		return "image/bmp"
+	case len(data) >= 6 && bytes.HasPrefix(data, []byte{0x00, 0x00, 0x01, 0x00}):
+		return "image/x-icon"
+	case len(data) >= 12 && bytes.HasPrefix(data, []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP")):
+		return "image/webp"
+	case isSVG(data):
+		return "image/svg+xml"
+	}
+	return ""
+}
+
+// isSVG reports whether data is an SVG document: its root element is <svg>,
+// preceded only by an XML declaration, comments and a DOCTYPE
+func isSVG(data []byte) bool {
+	if len(data) > sniffLength {
+		data = data[:sniffLength]
+	}
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+
+	for {
+		data = bytes.TrimLeft(data, " \t\r\n")
+
+		var end []byte
+		switch {
+		case bytes.HasPrefix(data, []byte("<?")):
+			end = []byte("?>")
+		case bytes.HasPrefix(data, []byte("<!--")):
+			end = []byte("-->")
+		case len(data) >= 9 && bytes.EqualFold(data[:9], []byte("<!DOCTYPE")):
+			end = []byte(">")
+			// An internal subset may contain '>'
+			if i := bytes.IndexAny(data, "[>"); i >= 0 && data[i] == '[' {
+				end = []byte("]>")
+			}
+		default:
+			return hasSVGRoot(data)
+		}
+
+		i := bytes.Index(data, end)
+		if i < 0 {
+			return false
+		}
+		data = data[i+len(end):]
+	}
+}
+
+// hasSVGRoot reports whether data starts with an <svg> start tag
+func hasSVGRoot(data []byte) bool {
+	if len(data) < 5 || !bytes.EqualFold(data[:4], []byte("<svg")) {
+		return false
+	}
+	switch data[4] {
+	case ' ', '\t', '\r', '\n', '>', '/':
+		return true
+	}
+	return false
+}
+
+// normalizeMimeType lowercases a MIME type, drops parameters and resolves aliases
+func normalizeMimeType(mimeType string) string {
+	if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+		mimeType = mediaType
+	}
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+
+	if canonical, ok := mimeAliases[mimeType]; ok {
+		return canonical
+	}
+	return mimeType
+}
+
+// NewResource creates a resource from byte data after checking that it is a
+// supported image. An empty mimeType is detected from the data; otherwise it
+// must match the content.
+func NewResource(data []byte, mimeType string) (*Resource, error) {
+	r := LoadResourceFromBytes(data, mimeType)
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Validate checks that the resource data is a supported image and matches
+// its MimeType
+func (r *Resource) Validate() error {
+	head, err := r.head(sniffLength)
+	if err != nil {
+		return err
+	}
+
+	sniffed := SniffMimeType(head)
+	if sniffed == "" {
+		return ErrNotImage
+	}
+
+	if declared := normalizeMimeType(r.MimeType); declared != sniffed {
+		return fmt.Errorf("gntp: resource declared as %s but contains %s", r.MimeType, sniffed)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package gntp
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSniffMimeType(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", "image/png"},
+		{"jpeg", "\xFF\xD8\xFF\xE0", "image/jpeg"},
+		{"gif", "GIF89a\x01\x00", "image/gif"},
+		{"webp", "RIFF\x00\x00\x00\x00WEBPVP8 ", "image/webp"},
+		{"svg", `<svg xmlns="http://www.w3.org/2000/svg"></svg>`, "image/svg+xml"},
+		{"svg with prolog", "\xEF\xBB\xBF<?xml version=\"1.0\"?>\n<!-- logo -->\n<!DOCTYPE svg PUBLIC \"-//W3C//DTD SVG 1.1//EN\" \"http://www.w3.org/Graphics/SVG/1.1/DTD/svg11.dtd\">\n<svg>", "image/svg+xml"},
+		{"svg with internal subset", `<!DOCTYPE svg [<!ENTITY a "<b>">]><svg/>`, "image/svg+xml"},
+		{"html containing svg", "<!DOCTYPE html><html><body><svg></svg></body></html>", ""},
+		{"xml containing svg", `<?xml version="1.0"?><root><svg/></root>`, ""},
+		{"svg-like element", "<svgfoo></svgfoo>", ""},
+		{"unterminated comment", "<!-- <svg>", ""},
+		{"text", "hello", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SniffMimeType([]byte(tt.data)); got != tt.want {
+				t.Errorf("SniffMimeType(%q) = %q, want %q", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewResourceRejectsHTML(t *testing.T) {
+	_, err := NewResource([]byte("<!DOCTYPE html><html><body><svg></svg></body></html>"), "")
+	if !errors.Is(err, ErrNotImage) {
+		t.Errorf("NewResource(HTML) = %v, want ErrNotImage", err)
+	}
+}
@@ -0,0 +1,260 @@
+package gntp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Problems reported in a ValidationError for templates
+var (
+	ErrTemplate   = errors.New("invalid template")
+	ErrNoTemplate = errors.New("notification type has no templates")
+)
+
+// templateFuncs are the helper functions available in title and text templates
+var templateFuncs = template.FuncMap{
+	"truncate":         templateTruncate,
+	"humanizeDuration": humanizeDuration,
+	"humanizeBytes":    humanizeBytes,
+	"join":             templateJoin,
+	"default":          templateDefault,
+	"tr":               (*localizer)(nil).translate,
+	"plural":           (*localizer)(nil).plural,
+}
+
+// notificationTemplates are the parsed templates of a notification type
+type notificationTemplates struct {
+	title *template.Template
+	text  *template.Template
+}
+
+// WithTemplates sets the text/template sources NotifyData renders the title
+// and text from. Either may be empty.
+func (nt *NotificationType) WithTemplates(title, text string) *NotificationType {
+	nt.TitleTemplate = title
+	nt.TextTemplate = text
+	return nt
+}
+
+// parseTemplates parses the title and text templates, or returns nil if the
+// notification type has none
+func (nt *NotificationType) parseTemplates() (*notificationTemplates, error) {
+	if nt.TitleTemplate == "" && nt.TextTemplate == "" {
+		return nil, nil
+	}
+
+	var errs []error
+	parse := func(part, source string) *template.Template {
+		if source == "" {
+			return nil
+		}
+		tmpl, err := template.New(nt.Name + " " + part).Funcs(templateFuncs).Parse(source)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrTemplate, err))
+		}
+		return tmpl
+	}
+
+	templates := &notificationTemplates{
+		title: parse("title", nt.TitleTemplate),
+		text:  parse("text", nt.TextTemplate),
+	}
+	if len(errs) > 0 {
+		return nil, validationError(errs)
+	}
+	return templates, nil
+}
+
+// render executes the templates with data, replacing the template functions
+// with funcs if given
+func (t *notificationTemplates) render(data any, funcs template.FuncMap) (title, text string, err error) {
+	execute := func(tmpl *template.Template) (string, error) {
+		if tmpl == nil {
+			return "", nil
+		}
+		if funcs != nil {
+			bound, err := tmpl.Clone()
+			if err != nil {
+				return "", fmt.Errorf("failed to render template: %w", err)
+			}
+			tmpl = bound.Funcs(funcs)
+		}
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, data); err != nil {
+			return "", fmt.Errorf("failed to render template: %w", err)
+		}
+		return sb.String(), nil
+	}
+
+	if title, err = execute(t.title); err != nil {
+		return "", "", err
+	}
+	if text, err = execute(t.text); err != nil {
+		return "", "", err
+	}
+	return title, text, nil
+}
+
+// NotifyData sends a notification whose title and text are rendered from
+// the templates of its notification type with data
+func (c *Client) NotifyData(notificationName string, data any) error {
+	return c.NotifyDataContext(context.Background(), notificationName, data, NewNotifyOptions())
+}
+
+// NotifyDataContext sends a notification whose title and text are rendered
+// from the templates of its notification type with data. The result is
+// truncated to the limits of the profile in effect. With a catalog, the
+// templates are translated for the destination locale.
+func (c *Client) NotifyDataContext(ctx context.Context, notificationName string, data any, options *NotifyOptions) error {
+	state := c.registered(c.ApplicationName)
+	if state == nil {
+		return fmt.Errorf("must call Register() before Notify()")
+	}
+
+	title, text, err := c.renderTemplates(state, notificationName, data)
+	if err != nil {
+		return err
+	}
+	return c.NotifyContext(ctx, notificationName, title, text, options)
+}
+
+// NotifyApplicationData is NotifyDataContext on behalf of app
+func (c *Client) NotifyApplicationData(ctx context.Context, app *Application, notificationName string, data any, options *NotifyOptions) error {
+	state := c.registered(app.Name)
+	if state == nil {
+		return errNotRegistered(app)
+	}
+
+	title, text, err := c.renderTemplates(state, notificationName, data)
+	if err != nil {
+		return err
+	}
+	return c.notifyApplication(ctx, app, notificationName, title, text, options)
+}
+
+// renderTemplates renders the templates registered for a notification type
+// and truncates the result to the profile's limits
+func (c *Client) renderTemplates(state *applicationState, notificationName string, data any) (string, string, error) {
+	templates, ok := state.templates[notificationName]
+	if !ok {
+		if !state.has(notificationName) {
+			return "", "", validationError([]error{fmt.Errorf("%w: %q", ErrUnregisteredNotification, notificationName)})
+		}
+		return "", "", validationError([]error{fmt.Errorf("%w: %q", ErrNoTemplate, notificationName)})
+	}
+
+	l := c.localizer()
+	templates, err := l.templates(state, state.notification(notificationName))
+	if err != nil {
+		return "", "", fmt.Errorf("notification %q: %w", notificationName, err)
+	}
+
+	title, text, err := templates.render(data, l.funcs())
+	if err != nil {
+		return "", "", fmt.Errorf("notification %q: %w", notificationName, err)
+	}
+
+	if profile := c.profile(); profile != nil {
+		title = truncate(title, profile.MaxTitleLength)
+		text = truncate(text, profile.MaxTextLength)
+	}
+	return title, text, nil
+}
+
+// templateTruncate is the truncate template function: {{.Text | truncate 80}}
+func templateTruncate(length int, s string) string {
+	return truncate(s, length)
+}
+
+// humanizeDuration formats a duration with its two largest units, e.g.
+// "2h 5m" or "45s"
+func humanizeDuration(d time.Duration) string {
+	if d < 0 {
+		return "-" + humanizeDuration(-d)
+	}
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+
+	units := []struct {
+		size time.Duration
+		name string
+	}{
+		{24 * time.Hour, "d"},
+		{time.Hour, "h"},
+		{time.Minute, "m"},
+		{time.Second, "s"},
+	}
+
+	var parts []string
+	d = d.Round(time.Second)
+	for _, unit := range units {
+		if d >= unit.size {
+			parts = append(parts, fmt.Sprintf("%d%s", d/unit.size, unit.name))
+			d %= unit.size
+		}
+		if len(parts) == 2 {
+			break
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// humanizeBytes formats a byte count of any integer or float type with
+// binary units, e.g. "1.5 MB"
+func humanizeBytes(n any) (string, error) {
+	var value float64
+	switch v := reflect.ValueOf(n); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		value = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		value = v.Float()
+	default:
+		return "", fmt.Errorf("humanizeBytes: not a number: %T", n)
+	}
+
+	const unit = 1024
+	if value < unit && value > -unit {
+		return fmt.Sprintf("%d B", int64(value)), nil
+	}
+
+	suffixes := []string{"KB", "MB", "GB", "TB", "PB", "EB"}
+	i := -1
+	for (value >= unit || value <= -unit) && i < len(suffixes)-1 {
+		value /= unit
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", value, suffixes[i]), nil
+}
+
+// templateJoin is the join template function: {{join ", " .Tags}}
+func templateJoin(sep string, items any) string {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return fmt.Sprint(items)
+	}
+
+	parts := make([]string, v.Len())
+	for i := range parts {
+		parts[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	return strings.Join(parts, sep)
+}
+
+// templateDefault is the default template function: {{.Host | default "unknown"}}
+func templateDefault(fallback, value any) any {
+	if value == nil {
+		return fallback
+	}
+	if v := reflect.ValueOf(value); v.IsZero() {
+		return fallback
+	}
+	return value
+}
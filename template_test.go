@@ -0,0 +1,177 @@
+package gntp
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		data     any
+		want     string
+	}{
+		{"truncate", `{{.S | truncate 6}}`, map[string]any{"S": "notification"}, "notif…"},
+		{"truncate short", `{{.S | truncate 20}}`, map[string]any{"S": "short"}, "short"},
+		{"truncate runes", `{{.S | truncate 3}}`, map[string]any{"S": "日本語テキスト"}, "日本…"},
+		{"duration", `{{humanizeDuration .D}}`, map[string]any{"D": 2*time.Hour + 5*time.Minute + 30*time.Second}, "2h 5m"},
+		{"duration days", `{{humanizeDuration .D}}`, map[string]any{"D": 26 * time.Hour}, "1d 2h"},
+		{"duration seconds", `{{humanizeDuration .D}}`, map[string]any{"D": 45 * time.Second}, "45s"},
+		{"duration sub-second", `{{humanizeDuration .D}}`, map[string]any{"D": 1500 * time.Microsecond}, "2ms"},
+		{"duration negative", `{{humanizeDuration .D}}`, map[string]any{"D": -90 * time.Second}, "-1m 30s"},
+		{"bytes", `{{humanizeBytes .N}}`, map[string]any{"N": 512}, "512 B"},
+		{"bytes KB", `{{humanizeBytes .N}}`, map[string]any{"N": uint64(1536)}, "1.5 KB"},
+		{"bytes MB", `{{humanizeBytes .N}}`, map[string]any{"N": 5 << 20}, "5.0 MB"},
+		{"bytes float", `{{humanizeBytes .N}}`, map[string]any{"N": 3.0 * (1 << 30)}, "3.0 GB"},
+		{"join", `{{join ", " .Tags}}`, map[string]any{"Tags": []string{"a", "b", "c"}}, "a, b, c"},
+		{"join ints", `{{join "+" .Tags}}`, map[string]any{"Tags": []int{1, 2}}, "1+2"},
+		{"join scalar", `{{join ", " .Tags}}`, map[string]any{"Tags": "solo"}, "solo"},
+		{"default empty", `{{.Host | default "unknown"}}`, map[string]any{"Host": ""}, "unknown"},
+		{"default missing", `{{.Host | default "unknown"}}`, map[string]any{}, "unknown"},
+		{"default set", `{{.Host | default "unknown"}}`, map[string]any{"Host": "db1"}, "db1"},
+		{"default zero", `{{.Count | default 1}}`, map[string]any{"Count": 0}, "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templates, err := NewNotificationType("test").WithTemplates(tt.template, "").parseTemplates()
+			if err != nil {
+				t.Fatal(err)
+			}
+			title, _, err := templates.render(tt.data, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if title != tt.want {
+				t.Errorf("%s = %q, want %q", tt.template, title, tt.want)
+			}
+		})
+	}
+}
+
+func TestHumanizeBytesRejectsNonNumbers(t *testing.T) {
+	if _, err := humanizeBytes("10"); err == nil {
+		t.Error("humanizeBytes(\"10\") succeeded")
+	}
+}
+
+func TestNotifyData(t *testing.T) {
+	type backup struct {
+		Name     string
+		Files    int
+		Duration time.Duration
+	}
+
+	tests := []struct {
+		name      string
+		profile   *Profile
+		title     string
+		text      string
+		data      any
+		wantTitle string
+		wantText  string
+	}{
+		{
+			name:      "title and text",
+			title:     "Backup {{.Name}} finished",
+			text:      "{{.Files}} files in {{humanizeDuration .Duration}}",
+			data:      backup{"home", 120, 95 * time.Second},
+			wantTitle: "Backup home finished",
+			wantText:  "120 files in 1m 35s",
+		},
+		{
+			name:      "text only",
+			text:      "{{.Name}}",
+			data:      backup{Name: "home"},
+			wantTitle: "",
+			wantText:  "home",
+		},
+		{
+			name:      "truncated after rendering",
+			profile:   &Profile{Name: "Pager", MaxTitleLength: 10, MaxTextLength: 8},
+			title:     "Backup {{.Name}} finished",
+			text:      "{{.Files}} files copied",
+			data:      backup{Name: "home", Files: 120},
+			wantTitle: "Backup ho…",
+			wantText:  "120 fil…",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t)
+			client := server.client("Templates")
+			if tt.profile != nil {
+				client.WithProfile(*tt.profile)
+			}
+			err := client.Register([]*NotificationType{NewNotificationType("backup").WithTemplates(tt.title, tt.text)})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := client.NotifyData("backup", tt.data); err != nil {
+				t.Fatal(err)
+			}
+			notify := server.received("NOTIFY")
+			if len(notify) != 1 {
+				t.Fatalf("received %d notifications, want 1", len(notify))
+			}
+			if got := notify[0].Headers["Notification-Title"]; got != tt.wantTitle {
+				t.Errorf("title = %q, want %q", got, tt.wantTitle)
+			}
+			if got := notify[0].Headers["Notification-Text"]; got != tt.wantText {
+				t.Errorf("text = %q, want %q", got, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestNotifyDataErrors(t *testing.T) {
+	server := newFakeServer(t)
+	client := server.client("Templates")
+
+	if err := client.NotifyData("backup", nil); err == nil {
+		t.Error("NotifyData before Register succeeded")
+	}
+
+	err := client.Register([]*NotificationType{NewNotificationType("backup").WithTemplates("{{.Name", "")})
+	if !errors.Is(err, ErrTemplate) {
+		t.Fatalf("Register with a bad template = %v, want ErrTemplate", err)
+	}
+
+	err = client.Register([]*NotificationType{
+		NewNotificationType("backup").WithTemplates("{{.Name}}", ""),
+		NewNotificationType("plain"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		notification string
+		data         any
+		want         error // nil for a render error
+	}{
+		{"unregistered", "missing", nil, ErrUnregisteredNotification},
+		{"no templates", "plain", nil, ErrNoTemplate},
+		{"missing field", "backup", struct{ Other string }{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := client.NotifyData(tt.notification, tt.data)
+			if err == nil {
+				t.Fatal("NotifyData succeeded")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("NotifyData = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if got := len(server.received("NOTIFY")); got != 0 {
+		t.Errorf("sent %d notifications that failed to render", got)
+	}
+}
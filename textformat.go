@@ -0,0 +1,252 @@
+package gntp
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// TextFormat is the markup of a notification text
+type TextFormat int
+
+const (
+	// TextPlain sends the text as is
+	TextPlain TextFormat = iota
+
+	// TextMarkdown converts Markdown to plain text
+	TextMarkdown
+
+	// TextHTML converts HTML to plain text
+	TextHTML
+)
+
+var (
+	// Markdown block syntax
+	mdFence      = regexp.MustCompile("^\\s*(```|~~~)")
+	mdHeading    = regexp.MustCompile(`^\s{0,3}#{1,6}\s+(.*?)\s*#*\s*$`)
+	mdQuote      = regexp.MustCompile(`^\s*>\s?`)
+	mdBullet     = regexp.MustCompile(`^(\s*)[-*+]\s+`)
+	mdRule       = regexp.MustCompile(`^\s{0,3}([-*_])(\s*([-*_])){2,}\s*$`)
+	mdCodeSpan   = regexp.MustCompile("`+([^`]+)`+")
+	mdImage      = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	mdLink       = regexp.MustCompile(`\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	mdAutolink   = regexp.MustCompile(`<((?:https?|mailto):[^>\s]+)>`)
+	mdBold       = regexp.MustCompile(`(\*\*|__)(\S(?:.*?\S)?)(\*\*|__)`)
+	mdItalicStar = regexp.MustCompile(`(^|\W)\*(\S(?:[^*]*\S)?)\*(\W|$)`)
+	mdItalicLine = regexp.MustCompile(`(^|\W)_(\S(?:[^_]*\S)?)_(\W|$)`)
+	mdStrike     = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+
+	// HTML
+	htmlDropped   = regexp.MustCompile(`(?is)<(script|style|head)\b.*?</(script|style|head)\s*>`)
+	htmlComment   = regexp.MustCompile(`(?s)<!--.*?-->`)
+	htmlLink      = regexp.MustCompile(`(?is)<a\s[^>]*?href\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))[^>]*>(.*?)</a\s*>`)
+	htmlBreak     = regexp.MustCompile(`(?i)<br\s*/?>`)
+	htmlBlockEnd  = regexp.MustCompile(`(?i)</(p|div|h[1-6]|tr|ul|ol|table|blockquote|pre)\s*>|<(p|div|h[1-6]|tr|ul|ol|table|blockquote|pre|hr)\b[^>]*>`)
+	htmlListItem  = regexp.MustCompile(`(?i)<li\b[^>]*>`)
+	htmlTag       = regexp.MustCompile(`(?s)<[^>]*>`)
+	htmlSpace     = regexp.MustCompile(`\s+`)
+	htmlLineSpace = regexp.MustCompile(`[ \t]*\n[ \t]*`)
+
+	// Text
+	bareURL    = regexp.MustCompile(`https?://[^\s<>()]+`)
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// WithTextFormat sets the markup of the text, converted to plain text before
+// sending
+func (no *NotifyOptions) WithTextFormat(format TextFormat) *NotifyOptions {
+	no.TextFormat = format
+	return no
+}
+
+// WithLinkCallback makes the first http or https link in the text the
+// CallbackTarget, unless a CallbackTarget is set
+func (no *NotifyOptions) WithLinkCallback(enabled bool) *NotifyOptions {
+	no.LinkCallback = enabled
+	return no
+}
+
+// RenderText converts text in the given format to plain text. Links become
+// "text (url)", list items start with "•", code is kept as is and HTML
+// entities are decoded. It also returns the first link's URL, or "".
+func RenderText(text string, format TextFormat) (plain, firstLink string) {
+	switch format {
+	case TextMarkdown:
+		return renderMarkdown(text)
+	case TextHTML:
+		return renderHTML(text)
+	default:
+		return text, bareURL.FindString(text)
+	}
+}
+
+// renderText applies options.TextFormat and LinkCallback, returning the text
+// and the options to send; options is copied if it changes
+func renderText(text string, options *NotifyOptions) (string, *NotifyOptions) {
+	if options.TextFormat == TextPlain && !options.LinkCallback {
+		return text, options
+	}
+
+	text, link := RenderText(text, options.TextFormat)
+	if options.LinkCallback && options.CallbackTarget == "" && link != "" {
+		copied := *options
+		copied.CallbackTarget = link
+		options = &copied
+	}
+	return text, options
+}
+
+// formatLink returns "text (url)", or just the URL if the text is empty or
+// the URL itself
+func formatLink(text, url string) string {
+	text = strings.TrimSpace(text)
+	if text == "" || text == url || strings.TrimPrefix(url, "mailto:") == text {
+		return url
+	}
+	return text + " (" + url + ")"
+}
+
+// renderMarkdown converts Markdown to plain text
+func renderMarkdown(text string) (string, string) {
+	var firstLink string
+	noteLink := func(url string) {
+		if firstLink == "" && url != "" {
+			firstLink = url
+		}
+	}
+
+	var out []string
+	inFence := false
+	for _, line := range strings.Split(normalizeLineEndings(text), "\n") {
+		if mdFence.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			out = append(out, line)
+			continue
+		}
+
+		if mdRule.MatchString(line) {
+			out = append(out, "")
+			continue
+		}
+		if m := mdHeading.FindStringSubmatch(line); m != nil {
+			line = m[1]
+		}
+		line = mdQuote.ReplaceAllString(line, "")
+		line = mdBullet.ReplaceAllString(line, "$1• ")
+
+		out = append(out, renderMarkdownInline(line, noteLink))
+	}
+
+	plain := blankLines.ReplaceAllString(strings.Join(out, "\n"), "\n\n")
+	return strings.TrimSpace(plain), firstLink
+}
+
+// renderMarkdownInline converts the inline syntax of one line. Code spans
+// are kept verbatim.
+func renderMarkdownInline(line string, noteLink func(url string)) string {
+	var rendered strings.Builder
+	last := 0
+	for _, span := range mdCodeSpan.FindAllStringSubmatchIndex(line, -1) {
+		rendered.WriteString(renderMarkdownText(line[last:span[0]], noteLink))
+		rendered.WriteString(line[span[2]:span[3]])
+		last = span[1]
+	}
+	rendered.WriteString(renderMarkdownText(line[last:], noteLink))
+	return rendered.String()
+}
+
+// renderMarkdownText converts links, emphasis and entities in text outside code spans
+func renderMarkdownText(s string, noteLink func(url string)) string {
+	s = mdImage.ReplaceAllString(s, "$1")
+	noteLink(firstWebLink(s))
+
+	s = mdLink.ReplaceAllStringFunc(s, func(m string) string {
+		parts := mdLink.FindStringSubmatch(m)
+		return formatLink(parts[1], parts[2])
+	})
+	s = mdAutolink.ReplaceAllString(s, "$1")
+
+	s = mdBold.ReplaceAllString(s, "$2")
+	s = mdStrike.ReplaceAllString(s, "$1")
+	s = replaceEmphasis(mdItalicStar, s)
+	s = replaceEmphasis(mdItalicLine, s)
+	return html.UnescapeString(s)
+}
+
+// replaceEmphasis strips the delimiters matched by re until none are left.
+// A match consumes the character after the closing delimiter, so adjacent
+// spans like "*a* *b*" take more than one pass.
+func replaceEmphasis(re *regexp.Regexp, s string) string {
+	for {
+		replaced := re.ReplaceAllString(s, "$1$2$3")
+		if replaced == s {
+			return s
+		}
+		s = replaced
+	}
+}
+
+// firstWebLink returns the http or https URL of the Markdown link, autolink
+// or bare URL that starts first in s, or ""
+func firstWebLink(s string) string {
+	first, firstURL := len(s), ""
+	for _, link := range []struct {
+		re    *regexp.Regexp
+		group int // Submatch holding the URL
+	}{{mdLink, 2}, {mdAutolink, 1}, {bareURL, 0}} {
+		for _, m := range link.re.FindAllStringSubmatchIndex(s, -1) {
+			url := s[m[2*link.group]:m[2*link.group+1]]
+			if !isWebURL(url) {
+				continue
+			}
+			if m[0] < first {
+				first, firstURL = m[0], url
+			}
+			break
+		}
+	}
+	return firstURL
+}
+
+// isWebURL reports whether url is an absolute http or https URL
+func isWebURL(url string) bool {
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
+
+// renderHTML converts HTML to plain text
+func renderHTML(text string) (string, string) {
+	var firstLink string
+
+	s := htmlComment.ReplaceAllString(text, "")
+	s = htmlDropped.ReplaceAllString(s, "")
+	s = htmlSpace.ReplaceAllString(s, " ")
+
+	s = htmlLink.ReplaceAllStringFunc(s, func(m string) string {
+		parts := htmlLink.FindStringSubmatch(m)
+		url := html.UnescapeString(parts[1] + parts[2] + parts[3])
+		if firstLink == "" && isWebURL(url) {
+			firstLink = url
+		}
+		label := html.UnescapeString(htmlTag.ReplaceAllString(parts[4], ""))
+		// Escape again so the final unescape leaves the link as is
+		return html.EscapeString(formatLink(label, url))
+	})
+
+	s = htmlBreak.ReplaceAllString(s, "\n")
+	s = htmlListItem.ReplaceAllString(s, "\n• ")
+	s = htmlBlockEnd.ReplaceAllString(s, "\n")
+	s = htmlTag.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+
+	s = htmlLineSpace.ReplaceAllString(s, "\n")
+	s = blankLines.ReplaceAllString(s, "\n\n")
+	s = strings.TrimSpace(s)
+
+	if firstLink == "" {
+		firstLink = bareURL.FindString(s)
+	}
+	return s, firstLink
+}
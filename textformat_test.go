@@ -0,0 +1,133 @@
+package gntp
+
+import "testing"
+
+func TestCallbackTargetWithoutListener(t *testing.T) {
+	server := newFakeServer(t)
+	client := server.client("Links")
+	if err := client.Register([]*NotificationType{NewNotificationType("news")}); err != nil {
+		t.Fatal(err)
+	}
+
+	options := NewNotifyOptions().
+		WithTextFormat(TextMarkdown).
+		WithLinkCallback(true)
+	if err := client.NotifyWithOptions("news", "Release", "See [notes](https://example.com/notes)", options); err != nil {
+		t.Fatal(err)
+	}
+
+	notify := server.received("NOTIFY")
+	if len(notify) != 1 {
+		t.Fatalf("received %d notifications, want 1", len(notify))
+	}
+	if got := notify[0].Headers["Notification-Callback-Target"]; got != "https://example.com/notes" {
+		t.Errorf("Notification-Callback-Target = %q, want the link", got)
+	}
+	if _, ok := notify[0].Headers["Notification-Callback-Context"]; ok {
+		t.Error("Notification-Callback-Context sent without a callback listener")
+	}
+}
+
+func TestRenderMarkdownEmphasis(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"*italic*", "italic"},
+		{"an *italic* word", "an italic word"},
+		{"*a* *b*", "a b"},
+		{"(*aside*)", "(aside)"},
+		{"5*3*2", "5*3*2"},
+		{"a*b*c", "a*b*c"},
+		{"_under_ and _score_", "under and score"},
+		{"snake_case_name", "snake_case_name"},
+		{"**bold** and *italic*", "bold and italic"},
+		{"`*code*`", "*code*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got, _ := RenderText(tt.text, TextMarkdown); got != tt.want {
+				t.Errorf("RenderText(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderTextFirstLink(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		format TextFormat
+		want   string
+	}{
+		{"bare before bracketed", "see https://a.example/x and [b](https://b.example)", TextMarkdown, "https://a.example/x"},
+		{"bracketed before bare", "[b](https://b.example) and https://a.example/x", TextMarkdown, "https://b.example"},
+		{"autolink", "mail <mailto:me@example.com> or <https://c.example>", TextMarkdown, "https://c.example"},
+		{"relative link skipped", "read [the docs](docs/readme.md) at https://d.example", TextMarkdown, "https://d.example"},
+		{"relative link only", "read [the docs](docs/readme.md)", TextMarkdown, ""},
+		{"image is not a link", "![logo](https://img.example/logo.png)", TextMarkdown, ""},
+		{"later line", "no link\n[e](https://e.example)", TextMarkdown, "https://e.example"},
+		{"html anchor", `<a href="/local">x</a> <a href="https://f.example">f</a>`, TextHTML, "https://f.example"},
+		{"html bare", "<p>visit https://g.example</p>", TextHTML, "https://g.example"},
+		{"plain", "visit https://h.example now", TextPlain, "https://h.example"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := RenderText(tt.text, tt.format); got != tt.want {
+				t.Errorf("RenderText(%q) link = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"paragraphs", "<p>One</p><p>Two</p>", "One\n\nTwo"},
+		{"line break", "a<br>b<br/>c", "a\nb\nc"},
+		{"list", "<ul><li>first</li><li>second</li></ul>", "• first\n• second"},
+		{"link", `<a href="https://example.com">Example</a>`, "Example (https://example.com)"},
+		{"link with same text", `<a href="https://example.com">https://example.com</a>`, "https://example.com"},
+		{"entities", "Tom &amp; Jerry &lt;3 &quot;x&quot; &#8212; caf&eacute;", `Tom & Jerry <3 "x" — café`},
+		{"entity in link", `<a href="https://example.com/?a=1&amp;b=2">A &amp; B</a>`, "A & B (https://example.com/?a=1&b=2)"},
+		{"script dropped", "<script>alert(1)</script><b>Bold</b>", "Bold"},
+		{"comment dropped", "before<!-- hidden -->after", "beforeafter"},
+		{"whitespace collapsed", "<p>  lots   of\n\tspace  </p>", "lots of space"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := RenderText(tt.html, TextHTML); got != tt.want {
+				t.Errorf("RenderText(%q) = %q, want %q", tt.html, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkCallbackIgnoresRelativeLinks(t *testing.T) {
+	server := newFakeServer(t)
+	client := server.client("Links")
+	if err := client.Register([]*NotificationType{NewNotificationType("news")}); err != nil {
+		t.Fatal(err)
+	}
+
+	options := NewNotifyOptions().
+		WithTextFormat(TextMarkdown).
+		WithLinkCallback(true)
+	if err := client.NotifyWithOptions("news", "Docs", "read [the docs](docs/readme.md)", options); err != nil {
+		t.Fatal(err)
+	}
+
+	notify := server.received("NOTIFY")
+	if len(notify) != 1 {
+		t.Fatalf("received %d notifications, want 1", len(notify))
+	}
+	if got, ok := notify[0].Headers["Notification-Callback-Target"]; ok {
+		t.Errorf("Notification-Callback-Target = %q for a relative link", got)
+	}
+}
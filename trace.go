@@ -0,0 +1,95 @@
+package gntp
+
+import (
+	"context"
+	"log/slog"
+)
+
+// TraceHeader carries the trace context of a NOTIFY request. Growl returns
+// Data- headers with callbacks, which links a callback to the span that
+// sent the notification.
+const TraceHeader = "Data-Trace-Parent"
+
+// Tracer creates spans around the send path and callbacks. Adapters for
+// tracing libraries such as OpenTelemetry implement it in a few lines; see
+// examples/tracing.
+type Tracer interface {
+	// Start begins a span named name as a child of the span in ctx
+	Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, Span)
+
+	// Inject returns the trace context of ctx as a header value, or "" if
+	// ctx has no span
+	Inject(ctx context.Context) string
+
+	// Extract returns ctx carrying the trace context from a header value
+	Extract(ctx context.Context, value string) context.Context
+}
+
+// Span is a single traced operation
+type Span interface {
+	// AddEvent records an event on the span
+	AddEvent(name string, attrs ...slog.Attr)
+
+	// RecordError marks the span as failed
+	RecordError(err error)
+
+	// End completes the span
+	End()
+}
+
+// NopTracer is the default Tracer; it records nothing
+type NopTracer struct{}
+
+// Start returns ctx and a span that does nothing
+func (NopTracer) Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+// Inject returns ""
+func (NopTracer) Inject(ctx context.Context) string {
+	return ""
+}
+
+// Extract returns ctx unchanged
+func (NopTracer) Extract(ctx context.Context, value string) context.Context {
+	return ctx
+}
+
+// nopSpan is the span returned by NopTracer
+type nopSpan struct{}
+
+func (nopSpan) AddEvent(name string, attrs ...slog.Attr) {}
+func (nopSpan) RecordError(err error)                    {}
+func (nopSpan) End()                                     {}
+
+// WithTracer sets the tracer used for requests and callbacks
+func (c *Client) WithTracer(tracer Tracer) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.tracer = tracer
+	return c
+}
+
+// tracing returns the client's tracer, or NopTracer
+func (c *Client) tracing() Tracer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.tracer == nil {
+		return NopTracer{}
+	}
+	return c.tracer
+}
+
+// traceSpan runs fn in a child span named name and records its error
+func traceSpan(ctx context.Context, tracer Tracer, name string, fn func(ctx context.Context) error) error {
+	ctx, span := tracer.Start(ctx, name)
+	defer span.End()
+
+	err := fn(ctx)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
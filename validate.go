@@ -0,0 +1,163 @@
+package gntp
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// Problems reported in a ValidationError
+var (
+	ErrEmptyName                = errors.New("empty name")
+	ErrDuplicateName            = errors.New("duplicate notification name")
+	ErrNoNotificationTypes      = errors.New("no notification types")
+	ErrUnregisteredNotification = errors.New("notification type not registered")
+	ErrPriorityOutOfRange       = errors.New("priority out of range -2 to 2")
+	ErrInvalidCallbackTarget    = errors.New("invalid callback target")
+	ErrTooLong                  = errors.New("too long for profile")
+)
+
+// ValidationError lists every problem found in a request before it was sent.
+// Use errors.Is with the Err values above to test for a specific problem.
+type ValidationError struct {
+	Problems []error
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	problems := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		problems[i] = problem.Error()
+	}
+	return "gntp: invalid request: " + strings.Join(problems, "; ")
+}
+
+// Unwrap returns the problems
+func (e *ValidationError) Unwrap() []error {
+	return e.Problems
+}
+
+// validationError returns a ValidationError for problems, or nil if there are none
+func validationError(problems []error) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: problems}
+}
+
+// problems returns the problems in err: those of a ValidationError, or err itself
+func problems(err error) []error {
+	if err == nil {
+		return nil
+	}
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return ve.Problems
+	}
+	return []error{err}
+}
+
+// Validate checks the notification type's name and templates before it is
+// registered
+func (nt *NotificationType) Validate() error {
+	var errs []error
+
+	if strings.TrimSpace(nt.Name) == "" {
+		errs = append(errs, fmt.Errorf("notification type: %w", ErrEmptyName))
+	}
+	if _, err := nt.parseTemplates(); err != nil {
+		errs = append(errs, problems(err)...)
+	}
+
+	return validationError(errs)
+}
+
+// Validate checks the priority and callback target
+func (no *NotifyOptions) Validate() error {
+	var errs []error
+
+	if no.Priority < -2 || no.Priority > 2 {
+		errs = append(errs, fmt.Errorf("%w: %d", ErrPriorityOutOfRange, no.Priority))
+	}
+
+	if no.CallbackTarget != "" {
+		u, err := url.Parse(no.CallbackTarget)
+		if err != nil || u.Scheme == "" || (u.Host == "" && u.Opaque == "") {
+			errs = append(errs, fmt.Errorf("%w: %q", ErrInvalidCallbackTarget, no.CallbackTarget))
+		}
+	}
+
+	return validationError(errs)
+}
+
+// Validate checks the application name and its notification types: there
+// must be at least one, and names must be non-empty and unique
+func (a *Application) Validate() error {
+	var errs []error
+
+	if strings.TrimSpace(a.Name) == "" {
+		errs = append(errs, fmt.Errorf("application: %w", ErrEmptyName))
+	}
+	if len(a.Notifications) == 0 {
+		errs = append(errs, ErrNoNotificationTypes)
+	}
+
+	seen := make(map[string]bool, len(a.Notifications))
+	for _, notif := range a.Notifications {
+		if notif == nil {
+			errs = append(errs, fmt.Errorf("notification type: %w", ErrEmptyName))
+			continue
+		}
+		errs = append(errs, problems(notif.Validate())...)
+
+		if seen[notif.Name] {
+			errs = append(errs, fmt.Errorf("%w: %q", ErrDuplicateName, notif.Name))
+		}
+		seen[notif.Name] = true
+	}
+
+	return validationError(errs)
+}
+
+// validateNotify checks a notification against the registration and options,
+// and the title and text lengths against a profile set with WithProfile
+func (c *Client) validateNotify(state *applicationState, notificationName, title, text string, options *NotifyOptions) error {
+	var errs []error
+
+	if !state.has(notificationName) {
+		errs = append(errs, fmt.Errorf("%w: %q", ErrUnregisteredNotification, notificationName))
+	}
+	errs = append(errs, problems(options.Validate())...)
+
+	c.mu.Lock()
+	profile := c.fixedProfile
+	c.mu.Unlock()
+
+	if profile != nil {
+		if n := utf8.RuneCountInString(title); profile.MaxTitleLength > 0 && n > profile.MaxTitleLength {
+			errs = append(errs, fmt.Errorf("title %w %s: %d characters, limit %d", ErrTooLong, profile.Name, n, profile.MaxTitleLength))
+		}
+		if n := utf8.RuneCountInString(text); profile.MaxTextLength > 0 && n > profile.MaxTextLength {
+			errs = append(errs, fmt.Errorf("text %w %s: %d characters, limit %d", ErrTooLong, profile.Name, n, profile.MaxTextLength))
+		}
+	}
+
+	return validationError(errs)
+}
+
+// has reports whether the notification type is registered
+func (s *applicationState) has(notificationName string) bool {
+	return s.notification(notificationName) != nil
+}
+
+// notification returns the registered notification type, or nil
+func (s *applicationState) notification(notificationName string) *NotificationType {
+	for _, notif := range s.notifications {
+		if notif.Name == notificationName {
+			return notif
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package gntp
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRegisterValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		types []*NotificationType
+		want  error
+	}{
+		{"no types", nil, ErrNoNotificationTypes},
+		{"duplicate names", []*NotificationType{NewNotificationType("a"), NewNotificationType("a")}, ErrDuplicateName},
+		{"empty name", []*NotificationType{NewNotificationType(" ")}, ErrEmptyName},
+		{"nil type", []*NotificationType{nil}, ErrEmptyName},
+		{"invalid template", []*NotificationType{NewNotificationType("a").WithTemplates("{{.Name", "")}, ErrTemplate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t)
+			err := server.client("Validate").Register(tt.types)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Register = %v, want %v", err, tt.want)
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) {
+				t.Errorf("Register = %T, want a *ValidationError", err)
+			}
+			if got := len(server.received("REGISTER")); got != 0 {
+				t.Errorf("sent %d invalid REGISTER requests", got)
+			}
+		})
+	}
+
+	if err := NewClient("").Register([]*NotificationType{NewNotificationType("a")}); !errors.Is(err, ErrEmptyName) {
+		t.Errorf("Register with an empty application name = %v, want ErrEmptyName", err)
+	}
+}
+
+func TestNotifyValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile *Profile
+		notif   string
+		title   string
+		options *NotifyOptions
+		want    error
+	}{
+		{"unregistered name", nil, "missing", "Title", NewNotifyOptions(), ErrUnregisteredNotification},
+		{"priority too high", nil, "job", "Title", rawPriority(3), ErrPriorityOutOfRange},
+		{"priority too low", nil, "job", "Title", rawPriority(-3), ErrPriorityOutOfRange},
+		{"relative callback target", nil, "job", "Title", NewNotifyOptions().WithCallbackTarget("docs/readme.md"), ErrInvalidCallbackTarget},
+		{"callback target without host", nil, "job", "Title", NewNotifyOptions().WithCallbackTarget("https://"), ErrInvalidCallbackTarget},
+		{"title too long", &Profile{Name: "Pager", MaxTitleLength: 5}, "job", "Too long", NewNotifyOptions(), ErrTooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t)
+			client := server.client("Validate")
+			if tt.profile != nil {
+				client.WithProfile(*tt.profile)
+			}
+			if err := client.Register([]*NotificationType{NewNotificationType("job")}); err != nil {
+				t.Fatal(err)
+			}
+
+			err := client.NotifyWithOptions(tt.notif, tt.title, "Text", tt.options)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Notify = %v, want %v", err, tt.want)
+			}
+			if got := len(server.received("NOTIFY")); got != 0 {
+				t.Errorf("sent %d invalid NOTIFY requests", got)
+			}
+		})
+	}
+}
+
+func TestNotifyValidationReportsEveryProblem(t *testing.T) {
+	server := newFakeServer(t)
+	client := server.client("Validate").WithProfile(Profile{Name: "Pager", MaxTextLength: 3})
+	if err := client.Register([]*NotificationType{NewNotificationType("job")}); err != nil {
+		t.Fatal(err)
+	}
+
+	options := rawPriority(5).WithCallbackTarget("nowhere")
+	err := client.NotifyWithOptions("missing", "Title", "Text", options)
+	for _, want := range []error{ErrUnregisteredNotification, ErrPriorityOutOfRange, ErrInvalidCallbackTarget, ErrTooLong} {
+		if !errors.Is(err, want) {
+			t.Errorf("Notify = %v, missing %v", err, want)
+		}
+	}
+}
+
+func TestTooLongIsTruncatedWhenDetected(t *testing.T) {
+	RegisterProfile(Profile{Name: "Pager", SoftwareNames: []string{"Test Pager"}, MaxTitleLength: 5})
+	t.Cleanup(func() {
+		profiles.Lock()
+		defer profiles.Unlock()
+		delete(profiles.bySoftware, "test pager")
+	})
+
+	server := newFakeServer(t)
+	server.setRespond(func(req *fakeRequest) string {
+		return "GNTP/1.0 -OK NONE\r\nResponse-Action: " + req.Type + "\r\nOrigin-Software-Name: Test Pager\r\n\r\n"
+	})
+	client := server.client("Validate")
+	if err := client.Register([]*NotificationType{NewNotificationType("job")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Notify("job", "Too long", "Text"); err != nil {
+		t.Fatalf("Notify with a detected profile = %v, want the title truncated", err)
+	}
+	if got := server.received("NOTIFY")[0].Headers["Notification-Title"]; got != "Too …" {
+		t.Errorf("title = %q, want %q", got, "Too …")
+	}
+}
+
+// rawPriority returns options with a priority WithPriority would clamp
+func rawPriority(priority int) *NotifyOptions {
+	options := NewNotifyOptions()
+	options.Priority = priority
+	return options
+}